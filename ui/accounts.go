@@ -0,0 +1,524 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"slices"
+	"strings"
+)
+
+// accessPrivilege describes an access bit for display in the account editor.
+type accessPrivilege struct {
+	Bit   int
+	Group string
+	Name  string
+}
+
+// accessPrivileges lists every access bit in the order and groups used by the classic Hotline account editor.
+var accessPrivileges = []accessPrivilege{
+	{hotline.AccessDeleteFile, "Files", "Can Delete Files"},
+	{hotline.AccessUploadFile, "Files", "Can Upload Files"},
+	{hotline.AccessDownloadFile, "Files", "Can Download Files"},
+	{hotline.AccessRenameFile, "Files", "Can Rename Files"},
+	{hotline.AccessMoveFile, "Files", "Can Move Files"},
+	{hotline.AccessCreateFolder, "Files", "Can Create Folders"},
+	{hotline.AccessDeleteFolder, "Files", "Can Delete Folders"},
+	{hotline.AccessRenameFolder, "Files", "Can Rename Folders"},
+	{hotline.AccessMoveFolder, "Files", "Can Move Folders"},
+	{hotline.AccessUploadFolder, "Files", "Can Upload Folders"},
+	{hotline.AccessDownloadFolder, "Files", "Can Download Folders"},
+	{hotline.AccessUploadAnywhere, "Files", "Can Upload Anywhere"},
+	{hotline.AccessSetFileComment, "Files", "Can Comment Files"},
+	{hotline.AccessSetFolderComment, "Files", "Can Comment Folders"},
+	{hotline.AccessViewDropBoxes, "Files", "Can View Drop Boxes"},
+	{hotline.AccessMakeAlias, "Files", "Can Make Aliases"},
+	{hotline.AccessCreateUser, "Users", "Can Create Accounts"},
+	{hotline.AccessDeleteUser, "Users", "Can Delete Accounts"},
+	{hotline.AccessOpenUser, "Users", "Can Read Accounts"},
+	{hotline.AccessModifyUser, "Users", "Can Modify Accounts"},
+	{hotline.AccessChangeOwnPass, "Users", "Can Change Own Password"},
+	{hotline.AccessDisconUser, "Users", "Can Disconnect Users"},
+	{hotline.AccessCannotBeDiscon, "Users", "Cannot be Disconnected"},
+	{hotline.AccessGetClientInfo, "Users", "Can Get User Info"},
+	{hotline.AccessNewsReadArt, "News", "Can Read Articles"},
+	{hotline.AccessNewsPostArt, "News", "Can Post Articles"},
+	{hotline.AccessNewsDeleteArt, "News", "Can Delete Articles"},
+	{hotline.AccessNewsCreateCat, "News", "Can Create Categories"},
+	{hotline.AccessNewsDeleteCat, "News", "Can Delete Categories"},
+	{hotline.AccessNewsCreateFldr, "News", "Can Create News Bundles"},
+	{hotline.AccessNewsDeleteFldr, "News", "Can Delete News Bundles"},
+	{hotline.AccessReadChat, "Chat", "Can Read Chat"},
+	{hotline.AccessSendChat, "Chat", "Can Send Chat"},
+	{hotline.AccessOpenChat, "Chat", "Can Initiate Private Chat"},
+	{hotline.AccessCloseChat, "Chat", "Can Close Chat"},
+	{hotline.AccessShowInList, "Chat", "Show in List"},
+	{hotline.AccessBroadcast, "Messaging", "Can Broadcast"},
+	{hotline.AccessSendPrivMsg, "Messaging", "Can Send Messages"},
+	{hotline.AccessAnyName, "Misc", "Can Use Any Name"},
+	{hotline.AccessNoAgreement, "Misc", "Don't Show Agreement"},
+}
+
+// account is a user account on the server as shown in the account administration screens.
+type account struct {
+	Login       string
+	Name        string
+	Access      hotline.AccessBitmap
+	HasPassword bool
+}
+
+// accountAdmin holds the state of the open account administration page.
+type accountAdmin struct {
+	accounts []account
+	marked   map[string]bool // Logins of accounts marked for a batch delete
+	list     *tview.List
+	footer   *tview.TextView
+}
+
+// parseFields parses the fields of an account record, which is a field count followed by the fields.
+func parseFields(data []byte) ([]hotline.Field, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("short account record: %d bytes", len(data))
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data[2:]))
+	scanner.Split(hotline.FieldScanner)
+
+	var fields []hotline.Field
+	for i := 0; i < int(binary.BigEndian.Uint16(data[0:2])); i++ {
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("account record has %d of %d fields", i, binary.BigEndian.Uint16(data[0:2]))
+		}
+
+		var field hotline.Field
+		if _, err := field.Write(scanner.Bytes()); err != nil {
+			return nil, fmt.Errorf("parse account field: %w", err)
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// encodeFields encodes fields as a field count followed by the fields, the format used for TranUpdateUser records.
+func encodeFields(fields ...hotline.Field) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(fields)))
+	for _, field := range fields {
+		b = append(b, field.Type[:]...)
+		b = append(b, field.FieldSize[:]...)
+		b = append(b, field.Data...)
+	}
+	return b
+}
+
+// parseAccount parses an account record from a TranListUsers reply.
+func (s *Session) parseAccount(data []byte) (account, error) {
+	fields, err := parseFields(data)
+	if err != nil {
+		return account{}, err
+	}
+
+	var acc account
+	for _, field := range fields {
+		switch field.Type {
+		case hotline.FieldUserName:
+			acc.Name = s.decodeText(field.Data)
+		case hotline.FieldUserLogin:
+			acc.Login = field.DecodeObfuscatedString()
+		case hotline.FieldUserAccess:
+			copy(acc.Access[:], field.Data)
+		case hotline.FieldUserPassword:
+			acc.HasPassword = true
+		}
+	}
+
+	return acc, nil
+}
+
+// openAccounts shows the account administration page and requests the list of accounts.
+func (s *Session) openAccounts() {
+	s.Pages.AddPage("accounts", s.renderAccounts(), true, true)
+	s.refreshAccounts()
+}
+
+// refreshAccounts requests the list of accounts from the server.
+func (s *Session) refreshAccounts() {
+	if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranListUsers, [2]byte{})); err != nil {
+		s.Logger.Error("Error requesting account list", "err", err)
+	}
+}
+
+func (s *Session) closeAccounts() {
+	s.accountAdmin = nil
+	s.Pages.RemovePage("accounts")
+}
+
+func (s *Session) renderAccounts() *tview.Flex {
+	admin := &accountAdmin{
+		marked: make(map[string]bool),
+		list:   tview.NewList().ShowSecondaryText(false),
+		footer: tview.NewTextView().SetDynamicColors(true),
+	}
+	s.accountAdmin = admin
+
+	admin.list.SetBorder(true).SetTitle("| Accounts |")
+	admin.list.SetSelectedFunc(func(i int, _ string, _ string, _ rune) {
+		s.getAccount(admin.accounts[i].Login)
+	})
+	admin.footer.SetText(s.accountShortcutText())
+
+	// current returns the selected account.
+	current := func() (account, bool) {
+		i := admin.list.GetCurrentItem()
+		if i < 0 || i >= len(admin.accounts) {
+			return account{}, false
+		}
+		return admin.accounts[i], true
+	}
+
+	admin.list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			s.closeAccounts()
+			return nil
+		case event.Rune() == 'n' && s.privileges.CreateAccounts:
+			s.showAccountEditor(nil)
+			return nil
+		case event.Rune() == ' ':
+			if acc, ok := current(); ok {
+				admin.marked[acc.Login] = !admin.marked[acc.Login]
+				s.renderAccountList()
+			}
+			return nil
+		case event.Rune() == 'd' && s.privileges.DeleteAccounts:
+			var logins []string
+			for _, acc := range admin.accounts {
+				if admin.marked[acc.Login] {
+					logins = append(logins, acc.Login)
+				}
+			}
+			if len(logins) == 0 {
+				if acc, ok := current(); ok {
+					logins = append(logins, acc.Login)
+				}
+			}
+			if len(logins) > 0 {
+				s.confirmDeleteAccounts(logins)
+			}
+			return nil
+		case event.Rune() == 'r':
+			s.refreshAccounts()
+			return nil
+		}
+		return event
+	})
+
+	accountsPage := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(admin.list, 0, 1, true).
+		AddItem(admin.footer, 2, 0, false)
+
+	return centered(accountsPage, 60, 24)
+}
+
+// accountShortcutText returns the account list keyboard shortcuts, with those we lack the privileges for dimmed.
+func (s *Session) accountShortcutText() string {
+	return strings.Join([]string{
+		shortcut("Enter", "Open", true),
+		shortcut("n", "New", s.privileges.CreateAccounts),
+		shortcut("Space", "Mark", true),
+		shortcut("d", "Delete", s.privileges.DeleteAccounts),
+		shortcut("r", "Reload", true),
+		shortcut("Esc", "Close", true),
+	}, "   ")
+}
+
+// renderAccountList redraws the account list, preserving the selection.
+func (s *Session) renderAccountList() {
+	admin := s.accountAdmin
+
+	selected := admin.list.GetCurrentItem()
+	admin.list.Clear()
+	for _, acc := range admin.accounts {
+		mark := " "
+		if admin.marked[acc.Login] {
+			mark = "[red]✗[-]"
+		}
+
+		var adminFlag string
+		if acc.Access.IsSet(hotline.AccessDisconUser) {
+			adminFlag = " [red::b]admin[-:-:-]"
+		}
+
+		admin.list.AddItem(fmt.Sprintf("%s %-16s [gray]%s[-]%s", mark, tview.Escape(acc.Login), tview.Escape(acc.Name), adminFlag), "", 0, nil)
+	}
+	admin.list.SetCurrentItem(selected)
+	admin.list.SetTitle(fmt.Sprintf("| Accounts (%d) |", len(admin.accounts)))
+}
+
+// HandleListUsers displays the accounts returned by the server.
+func (s *Session) HandleListUsers(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+		return res, err
+	}
+
+	var accounts []account
+	for _, field := range t.Fields {
+		if field.Type != hotline.FieldData {
+			continue
+		}
+
+		acc, err := s.parseAccount(field.Data)
+		if err != nil {
+			c.Logger.Error("Error parsing account", "err", err)
+			continue
+		}
+		accounts = append(accounts, acc)
+	}
+	slices.SortFunc(accounts, func(a, b account) int {
+		return cmp.Compare(strings.ToLower(a.Login), strings.ToLower(b.Login))
+	})
+
+	// The account list belongs to the UI goroutine, which can close it at any time.
+	s.App.QueueUpdateDraw(func() {
+		admin := s.accountAdmin
+		if admin == nil {
+			return
+		}
+
+		// Drop marks for accounts that no longer exist.
+		for login := range admin.marked {
+			if !slices.ContainsFunc(accounts, func(acc account) bool { return acc.Login == login }) {
+				delete(admin.marked, login)
+			}
+		}
+
+		admin.accounts = accounts
+		s.renderAccountList()
+	})
+
+	return res, err
+}
+
+// getAccount requests the account from the server for editing.
+func (s *Session) getAccount(login string) {
+	t := hotline.NewTransaction(hotline.TranGetUser, [2]byte{},
+		hotline.NewField(hotline.FieldUserLogin, []byte(login)),
+	)
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error requesting account", "err", err)
+	}
+}
+
+// HandleGetUser opens the account returned by the server in the account editor.
+func (s *Session) HandleGetUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+		return res, err
+	}
+
+	acc := account{
+		Login: t.GetField(hotline.FieldUserLogin).DecodeObfuscatedString(),
+		Name:  s.decodeText(t.GetField(hotline.FieldUserName).Data),
+	}
+	copy(acc.Access[:], t.GetField(hotline.FieldUserAccess).Data)
+
+	s.App.QueueUpdateDraw(func() {
+		// The account list knows whether the account has a password; TranGetUser only returns the password hash.
+		if s.accountAdmin != nil {
+			if i := slices.IndexFunc(s.accountAdmin.accounts, func(a account) bool { return a.Login == acc.Login }); i >= 0 {
+				acc.HasPassword = s.accountAdmin.accounts[i].HasPassword
+			}
+		}
+
+		s.showAccountEditor(&acc)
+	})
+
+	return res, err
+}
+
+// showAccountEditor shows the editor for an existing account, or for a new account if acc is nil.
+func (s *Session) showAccountEditor(acc *account) {
+	isNew := acc == nil
+	if isNew {
+		acc = &account{}
+	}
+
+	editor := tview.NewForm()
+	editor.
+		AddInputField("Login", acc.Login, 0, nil, nil).
+		AddInputField("Name", acc.Name, 0, nil, nil).
+		AddPasswordField("Password", "", 0, '*', nil)
+	if !isNew {
+		editor.AddCheckbox("Remove Password", false, nil)
+	}
+
+	// Index of the first access checkbox in the form
+	firstAccessItem := editor.GetFormItemCount()
+	for _, p := range accessPrivileges {
+		editor.AddCheckbox(fmt.Sprintf("%-9s %s", p.Group, p.Name), acc.Access.IsSet(p.Bit), nil)
+	}
+
+	title := "| New Account |"
+	if !isNew {
+		title = fmt.Sprintf("| Account: %s |", tview.Escape(acc.Login))
+	}
+
+	editor.AddButton("Save", func() {
+		updated := account{
+			Login: strings.TrimSpace(editor.GetFormItem(0).(*tview.InputField).GetText()),
+			Name:  editor.GetFormItem(1).(*tview.InputField).GetText(),
+		}
+		if updated.Login == "" {
+			editor.SetTitle(title + " Login is required |")
+			return
+		}
+		for i, p := range accessPrivileges {
+			if editor.GetFormItem(firstAccessItem + i).(*tview.Checkbox).IsChecked() {
+				updated.Access.Set(p.Bit)
+			}
+		}
+
+		password := editor.GetFormItem(2).(*tview.InputField).GetText()
+		removePassword := !isNew && editor.GetFormItem(3).(*tview.Checkbox).IsChecked()
+
+		var err error
+		switch {
+		case isNew:
+			err = s.newAccount(updated, password)
+		case updated.Login != acc.Login:
+			err = s.renameAccount(acc.Login, updated, password, removePassword)
+		default:
+			err = s.setAccount(updated, password, removePassword)
+		}
+		if err != nil {
+			s.Logger.Error("Error saving account", "err", err)
+		}
+	})
+	editor.AddButton("Cancel", func() {
+		s.Pages.RemovePage("accountEditor")
+	})
+	editor.SetCancelFunc(func() {
+		s.Pages.RemovePage("accountEditor")
+	})
+
+	editor.SetBorder(true).SetTitle(title)
+
+	s.Pages.RemovePage("accountEditor")
+	s.Pages.AddPage("accountEditor", centered(editor, 60, 30), true, true)
+}
+
+// accountPassword returns the password field for updating an account.  The server keeps the current password when
+// the field contains a single zero byte, and removes it when the field is missing.
+func accountPassword(password string, removePassword bool) []hotline.Field {
+	switch {
+	case removePassword:
+		return nil
+	case password == "":
+		return []hotline.Field{hotline.NewField(hotline.FieldUserPassword, []byte{0})}
+	default:
+		return []hotline.Field{hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte(password)))}
+	}
+}
+
+func (s *Session) newAccount(acc account, password string) error {
+	t := hotline.NewTransaction(hotline.TranNewUser, [2]byte{},
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(acc.Login))),
+		hotline.NewField(hotline.FieldUserName, s.encodeText(acc.Name)),
+		hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte(password))),
+		hotline.NewField(hotline.FieldUserAccess, acc.Access[:]),
+	)
+	return s.HLClient.Send(t)
+}
+
+func (s *Session) setAccount(acc account, password string, removePassword bool) error {
+	fields := []hotline.Field{
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(acc.Login))),
+		hotline.NewField(hotline.FieldUserName, s.encodeText(acc.Name)),
+		hotline.NewField(hotline.FieldUserAccess, acc.Access[:]),
+	}
+	fields = append(fields, accountPassword(password, removePassword)...)
+
+	return s.HLClient.Send(hotline.NewTransaction(hotline.TranSetUser, [2]byte{}, fields...))
+}
+
+// renameAccount changes the login of an account along with its other settings.  TranSetUser can't change the login,
+// so this uses the batch TranUpdateUser with a single record.
+func (s *Session) renameAccount(prevLogin string, acc account, password string, removePassword bool) error {
+	subFields := []hotline.Field{
+		hotline.NewField(hotline.FieldData, hotline.EncodeString([]byte(prevLogin))),
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(acc.Login))),
+		hotline.NewField(hotline.FieldUserName, s.encodeText(acc.Name)),
+		hotline.NewField(hotline.FieldUserAccess, acc.Access[:]),
+	}
+	subFields = append(subFields, accountPassword(password, removePassword)...)
+
+	t := hotline.NewTransaction(hotline.TranUpdateUser, [2]byte{},
+		hotline.NewField(hotline.FieldData, encodeFields(subFields...)),
+	)
+	return s.HLClient.Send(t)
+}
+
+// deleteAccounts deletes the accounts.  A single account is deleted with TranDeleteUser, and several at once with a
+// TranUpdateUser record per account.
+func (s *Session) deleteAccounts(logins []string) error {
+	if len(logins) == 1 {
+		t := hotline.NewTransaction(hotline.TranDeleteUser, [2]byte{},
+			hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(logins[0]))),
+		)
+		return s.HLClient.Send(t)
+	}
+
+	var records []hotline.Field
+	for _, login := range logins {
+		records = append(records, hotline.NewField(hotline.FieldData,
+			encodeFields(hotline.NewField(hotline.FieldData, hotline.EncodeString([]byte(login)))),
+		))
+	}
+	return s.HLClient.Send(hotline.NewTransaction(hotline.TranUpdateUser, [2]byte{}, records...))
+}
+
+func (s *Session) confirmDeleteAccounts(logins []string) {
+	text := fmt.Sprintf("Delete the account %s?", logins[0])
+	if len(logins) > 1 {
+		text = fmt.Sprintf("Delete %d accounts?\n\n%s", len(logins), strings.Join(logins, ", "))
+	}
+
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Cancel", "Delete"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonIndex == 1 {
+				if err := s.deleteAccounts(logins); err != nil {
+					s.Logger.Error("Error deleting accounts", "err", err)
+				}
+			}
+			s.Pages.RemovePage("deleteAccounts")
+		})
+
+	s.Pages.AddPage("deleteAccounts", modal, false, true)
+}
+
+// HandleAccountReply handles replies to account changes by closing the editor and reloading the account list.
+func (s *Session) HandleAccountReply(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+		return res, err
+	}
+
+	s.App.QueueUpdateDraw(func() {
+		s.Pages.RemovePage("accountEditor")
+		if s.accountAdmin != nil {
+			clear(s.accountAdmin.marked)
+			s.refreshAccounts()
+		}
+	})
+
+	return res, err
+}
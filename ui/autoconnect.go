@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// startupTarget is a server to connect to when the client starts.
+type startupTarget struct {
+	bookmark *Bookmark // Copy of the server's bookmark, if it's bookmarked
+	url      *hotlineURL
+}
+
+// SetStartupConnect makes the client connect to the bookmark with the given name, or to the server of a hotline://
+// URL, when it starts, instead of to the bookmarks marked to connect at startup.
+func (mhc *Client) SetStartupConnect(target string) error {
+	if isHotlineURL(target) {
+		u, err := parseHotlineURL(target)
+		if err != nil {
+			return err
+		}
+		t := startupTarget{url: u}
+		if b := mhc.Pref.bookmarkFor(u.Addr); b != nil {
+			bookmark := *b
+			t.bookmark = &bookmark
+		}
+		mhc.startupTargets = []startupTarget{t}
+		return nil
+	}
+
+	b := mhc.Pref.bookmarkNamed(target)
+	if b == nil {
+		return fmt.Errorf("no bookmark named %q", target)
+	}
+	bookmark := *b
+	mhc.startupTargets = []startupTarget{{bookmark: &bookmark}}
+	return nil
+}
+
+// bookmarkNamed returns the bookmark with the name, ignoring case, or nil if there is none.
+func (cp *ClientPrefs) bookmarkNamed(name string) *Bookmark {
+	for i := range cp.Bookmarks {
+		if strings.EqualFold(cp.Bookmarks[i].Name, name) {
+			return &cp.Bookmarks[i]
+		}
+	}
+	return nil
+}
+
+// autoConnectTargets returns the servers to connect to at startup: the one given on the command line, or else the
+// bookmarks marked to connect at startup.
+func (mhc *Client) autoConnectTargets() []startupTarget {
+	if mhc.startupTargets != nil {
+		return mhc.startupTargets
+	}
+
+	var targets []startupTarget
+	for _, b := range mhc.Pref.Bookmarks {
+		if b.AutoConnect {
+			targets = append(targets, startupTarget{bookmark: &b})
+		}
+	}
+	return targets
+}
+
+// autoConnect joins the startup servers.  Servers whose password is in the vault are joined once it's unlocked, so the
+// passphrase is asked for at most once.
+func (mhc *Client) autoConnect() {
+	var locked []startupTarget
+	for _, t := range mhc.autoConnectTargets() {
+		if t.needsVault() && mhc.vault == nil {
+			locked = append(locked, t)
+			continue
+		}
+		mhc.connectTarget(t)
+	}
+
+	if len(locked) > 0 {
+		mhc.unlockVault(func() {
+			for _, t := range locked {
+				mhc.connectTarget(t)
+			}
+		}, nil)
+	}
+}
+
+// needsVault reports whether the target's password has to be read from the vault.
+func (t startupTarget) needsVault() bool {
+	if t.bookmark == nil || t.bookmark.PasswordRef == "" {
+		return false
+	}
+	return t.url == nil || (t.url.Password == "" && t.url.Login == t.bookmark.Login)
+}
+
+// connectTarget joins a startup server, showing an error if it can't be reached.
+func (mhc *Client) connectTarget(t startupTarget) {
+	var name, addr, login, password string
+	var useTLS bool
+
+	if b := t.bookmark; b != nil {
+		name, addr, login, useTLS = b.Name, b.Addr, b.Login, b.TLS
+		password = b.Password
+		if b.PasswordRef != "" && mhc.vault != nil {
+			password = mhc.vault.entries[b.PasswordRef]
+		}
+	}
+	if u := t.url; u != nil {
+		if t.bookmark == nil || u.Login != t.bookmark.Login {
+			name = fmt.Sprintf("%s@%s", u.Login, u.Addr)
+			password = ""
+		}
+		addr, login = u.Addr, u.Login
+		if u.Password != "" {
+			password = u.Password
+		}
+	}
+
+	err := mhc.joinServer(name, addr, login, password, useTLS, t.url)
+
+	var certErr *untrustedCertError
+	if errors.As(err, &certErr) {
+		mhc.confirmTrustCert(certErr, func() { mhc.connectTarget(t) })
+		return
+	}
+	if err != nil {
+		mhc.Logger.Error("Error connecting at startup", "addr", addr, "err", err)
+
+		modal := tview.NewModal().
+			AddButtons([]string{"Ok"}).
+			SetText(fmt.Sprintf("Couldn't connect to %s:\n\n%v", name, err)).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				mhc.Pages.RemovePage("autoConnectErr")
+			})
+		modal.Box.SetTitle("Server Connection Error")
+
+		mhc.Pages.AddPage("autoConnectErr", modal, false, true)
+	}
+}
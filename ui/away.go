@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"time"
+)
+
+// idleCheckInterval is how often the client checks whether the user has become idle.
+const idleCheckInterval = 30 * time.Second
+
+// setAway sets our away status on the server.
+func (s *Session) setAway(away bool) {
+	if s.away == away {
+		return
+	}
+	s.away = away
+
+	if err := s.sendUserInfo(); err != nil {
+		s.Logger.Error("Error setting away status", "err", err)
+		return
+	}
+
+	status := "You are no longer away"
+	if away {
+		status = "You are now away"
+	}
+	s.chatNotice("%s", status)
+}
+
+// toggleAway manually sets or clears our away status.
+func (s *Session) toggleAway() {
+	s.idleAway = false
+	s.setAway(!s.away)
+}
+
+// userActivity records keyboard activity and returns from an idle away status on every server.
+func (mhc *Client) userActivity() {
+	mhc.lastActivity = time.Now()
+
+	for _, s := range mhc.sessions {
+		if s.idleAway {
+			s.idleAway = false
+			s.setAway(false)
+		}
+	}
+}
+
+// watchIdle periodically checks whether the user has been idle long enough to be marked away.
+func (mhc *Client) watchIdle() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		mhc.App.QueueUpdate(mhc.checkIdle)
+	}
+}
+
+func (mhc *Client) checkIdle() {
+	if mhc.Pref.AwayAfterMinutes <= 0 {
+		return
+	}
+
+	if time.Since(mhc.lastActivity) < time.Duration(mhc.Pref.AwayAfterMinutes)*time.Minute {
+		return
+	}
+
+	for _, s := range mhc.sessions {
+		if !s.away {
+			s.idleAway = true
+			s.setAway(true)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package ui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// bookmarksChanged saves the bookmarks after they were added, edited, removed or reordered, and points the sessions
+// at their bookmarks again, since changing the bookmark list can move them in memory.
+func (mhc *Client) bookmarksChanged() {
+	if err := mhc.savePrefs(); err != nil {
+		mhc.Logger.Error("Error saving bookmarks", "err", err)
+	}
+
+	for _, s := range mhc.sessions {
+		s.bookmark = mhc.Pref.bookmarkFor(s.serverAddr)
+	}
+}
+
+// showBookmarks renders the bookmark manager, which lists bookmarks in their groups.
+func (mhc *Client) showBookmarks() *tview.Flex {
+	root := tview.NewTreeNode("Bookmarks")
+	tree := tview.NewTreeView().SetRoot(root).SetTopLevel(1)
+	tree.SetBorder(true).SetTitle("| Bookmarks |")
+
+	footer := tview.NewTextView().SetDynamicColors(true)
+	footer.SetText(strings.Join([]string{
+		shortcut("Enter", "Connect", true),
+		shortcut("a", "Add", true),
+		shortcut("e", "Edit", true),
+		shortcut("c", "Duplicate", true),
+		shortcut("d", "Delete", true),
+		shortcut("K/J", "Move Up/Down", true),
+		shortcut("Esc", "Close", true),
+	}, "  "))
+
+	// render rebuilds the tree, selecting the bookmark at index selected.
+	var render func(selected int)
+	render = func(selected int) {
+		root.ClearChildren()
+
+		groups := make(map[string]*tview.TreeNode)
+		var selectedNode *tview.TreeNode
+		for i, b := range mhc.Pref.Bookmarks {
+			node := tview.NewTreeNode(fmt.Sprintf("%s  [gray]%s[-]", tview.Escape(b.Name), tview.Escape(b.Addr))).
+				SetReference(i)
+			if i == selected {
+				selectedNode = node
+			}
+
+			if b.Group == "" {
+				root.AddChild(node)
+				continue
+			}
+
+			group, ok := groups[b.Group]
+			if !ok {
+				group = tview.NewTreeNode(fmt.Sprintf("[::b]%s/[::-]", tview.Escape(b.Group))).
+					SetColor(tcell.ColorYellow).
+					SetReference(b.Group)
+				groups[b.Group] = group
+				root.AddChild(group)
+			}
+			group.AddChild(node)
+		}
+
+		switch {
+		case selectedNode != nil:
+			tree.SetCurrentNode(selectedNode)
+		case len(root.GetChildren()) > 0:
+			tree.SetCurrentNode(root.GetChildren()[0])
+		}
+	}
+	render(0)
+
+	// selected returns the index of the selected bookmark.
+	selected := func() (int, bool) {
+		node := tree.GetCurrentNode()
+		if node == nil {
+			return 0, false
+		}
+		i, ok := node.GetReference().(int)
+		return i, ok
+	}
+
+	// move swaps the selected bookmark with the previous (delta -1) or next (delta 1) bookmark in the same group.
+	move := func(delta int) {
+		i, ok := selected()
+		if !ok {
+			return
+		}
+		bookmarks := mhc.Pref.Bookmarks
+		for j := i + delta; j >= 0 && j < len(bookmarks); j += delta {
+			if bookmarks[j].Group == bookmarks[i].Group {
+				bookmarks[i], bookmarks[j] = bookmarks[j], bookmarks[i]
+				mhc.bookmarksChanged()
+				render(j)
+				return
+			}
+		}
+	}
+
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		i, ok := node.GetReference().(int)
+		if !ok {
+			node.SetExpanded(!node.IsExpanded())
+			return
+		}
+		b := mhc.Pref.Bookmarks[i]
+
+		open := func(password string) {
+			mhc.Pages.RemovePage("joinServer")
+			newJS := mhc.renderJoinServerForm(b.Name, b.Addr, b.Login, password, "bookmarks", true, true)
+			mhc.Pages.AddPage("joinServer", newJS, true, true)
+		}
+		// Without the vault, the password has to be entered by hand.
+		mhc.bookmarkPassword(b, open, func() { open("") })
+	})
+
+	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			mhc.Pages.SwitchToPage("home")
+			return nil
+		}
+
+		switch event.Rune() {
+		case 'a':
+			var group string
+			if i, ok := selected(); ok {
+				group = mhc.Pref.Bookmarks[i].Group
+			} else if node := tree.GetCurrentNode(); node != nil {
+				group, _ = node.GetReference().(string)
+			}
+			mhc.showBookmarkEditor(-1, Bookmark{Group: group, Login: hotline.GuestAccount}, "", render)
+		case 'e':
+			if i, ok := selected(); ok {
+				b := mhc.Pref.Bookmarks[i]
+				mhc.bookmarkPassword(b, func(password string) {
+					mhc.showBookmarkEditor(i, b, password, render)
+				}, nil)
+			}
+		case 'c':
+			if i, ok := selected(); ok {
+				b := mhc.Pref.Bookmarks[i]
+				b.Name += " copy"
+				b.AutoConnect = false
+				// The copy gets its own vault entry, so changing one password doesn't change the other.
+				mhc.bookmarkPassword(b, func(password string) {
+					if b.PasswordRef != "" {
+						b.PasswordRef = ""
+						if err := mhc.setBookmarkPassword(&b, password); err != nil {
+							mhc.Logger.Error("Error saving password to vault", "err", err)
+						}
+					}
+					mhc.Pref.Bookmarks = slices.Insert(mhc.Pref.Bookmarks, i+1, b)
+					mhc.bookmarksChanged()
+					render(i + 1)
+				}, nil)
+			}
+		case 'd':
+			if i, ok := selected(); ok {
+				mhc.confirmDeleteBookmark(i, render)
+			}
+		case 'K':
+			move(-1)
+		case 'J':
+			move(1)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	return tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tree, 0, 1, true).
+		AddItem(footer, 1, 0, false)
+}
+
+// confirmDeleteBookmark asks whether to delete the bookmark at index i, then re-renders the bookmark manager.
+func (mhc *Client) confirmDeleteBookmark(i int, render func(int)) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Delete the bookmark %s?", mhc.Pref.Bookmarks[i].Name)).
+		AddButtons([]string{"Cancel", "Delete"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			mhc.Pages.RemovePage("deleteBookmark")
+			if buttonIndex != 1 {
+				return
+			}
+
+			// The vault entry is left behind if the vault is locked; it's harmless, just unreachable.
+			if b := mhc.Pref.Bookmarks[i]; b.PasswordRef != "" && mhc.vault != nil {
+				if err := mhc.setBookmarkPassword(&b, ""); err != nil {
+					mhc.Logger.Error("Error removing password from vault", "err", err)
+				}
+			}
+			mhc.Pref.Bookmarks = slices.Delete(mhc.Pref.Bookmarks, i, i+1)
+			mhc.bookmarksChanged()
+			render(max(i-1, 0))
+		})
+
+	mhc.Pages.AddPage("deleteBookmark", modal, false, true)
+}
+
+// showBookmarkEditor shows a form for editing a bookmark, or adding it if i is -1, then re-renders the bookmark
+// manager.  Settings not shown in the form, such as the text encoding and TLS pin, are kept.  The password, which may be
+// in the vault, is passed separately.
+func (mhc *Client) showBookmarkEditor(i int, b Bookmark, password string, render func(int)) {
+	title := "| Edit Bookmark |"
+	if i < 0 {
+		title = "| New Bookmark |"
+	}
+
+	var iconStr string
+	if b.IconID != 0 {
+		iconStr = strconv.Itoa(b.IconID)
+	}
+
+	form := tview.NewForm()
+	form.
+		AddInputField("Name", b.Name, 0, nil, nil).
+		AddInputField("Address", b.Addr, 0, nil, nil).
+		AddInputField("Login", b.Login, 0, nil, nil).
+		AddPasswordField("Password", password, 0, '*', nil).
+		AddInputField("Group", b.Group, 0, nil, nil).
+		AddInputField("Your Name", b.Username, 0, nil, nil).
+		AddInputField("IconID", iconStr, 0, func(idStr string, _ rune) bool {
+			_, err := strconv.Atoi(idStr)
+			return err == nil
+		}, nil).
+		AddCheckbox("TLS", b.TLS, nil).
+		AddCheckbox("Connect at Startup", b.AutoConnect, nil).
+		AddCheckbox("Reconnect Automatically", b.AutoReconnect, nil)
+
+	form.
+		AddButton("Save", func() {
+			b.Name = strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+			b.Addr = strings.TrimSpace(form.GetFormItem(1).(*tview.InputField).GetText())
+			b.Login = form.GetFormItem(2).(*tview.InputField).GetText()
+			newPassword := form.GetFormItem(3).(*tview.InputField).GetText()
+			b.Group = strings.TrimSpace(form.GetFormItem(4).(*tview.InputField).GetText())
+			b.Username = form.GetFormItem(5).(*tview.InputField).GetText()
+			b.IconID, _ = strconv.Atoi(form.GetFormItem(6).(*tview.InputField).GetText())
+			b.TLS = form.GetFormItem(7).(*tview.Checkbox).IsChecked()
+			b.AutoConnect = form.GetFormItem(8).(*tview.Checkbox).IsChecked()
+			b.AutoReconnect = form.GetFormItem(9).(*tview.Checkbox).IsChecked()
+
+			if b.Addr == "" {
+				form.SetTitle("| Address is required |")
+				form.SetFocus(1)
+				return
+			}
+			if b.Name == "" {
+				b.Name = b.Addr
+			}
+
+			// A plaintext password from an old config is moved into the vault even if it wasn't changed.
+			changed := newPassword != password || b.Password != ""
+			save := func() {
+				if changed {
+					if err := mhc.setBookmarkPassword(&b, newPassword); err != nil {
+						mhc.Logger.Error("Error saving password to vault", "err", err)
+					}
+				}
+
+				if i < 0 {
+					mhc.Pref.Bookmarks = append(mhc.Pref.Bookmarks, b)
+					i = len(mhc.Pref.Bookmarks) - 1
+				} else {
+					mhc.Pref.Bookmarks[i] = b
+				}
+				mhc.bookmarksChanged()
+
+				mhc.Pages.RemovePage("bookmarkEditor")
+				render(i)
+			}
+			if changed && newPassword != "" {
+				mhc.unlockVault(save, nil)
+				return
+			}
+			save()
+		}).
+		AddButton("Cancel", func() {
+			mhc.Pages.RemovePage("bookmarkEditor")
+		})
+	form.SetBorder(true).SetTitle(title)
+	form.SetCancelFunc(func() {
+		mhc.Pages.RemovePage("bookmarkEditor")
+	})
+
+	mhc.Pages.AddPage("bookmarkEditor", centered(form, 50, 25), true, true)
+}
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// defaultEncoding is the text encoding used by classic Mac OS Hotline clients and servers.
+const defaultEncoding = "macroman"
+
+// textEncodings maps the names accepted by Bookmark.Encoding to their text encoding.  A nil encoding passes text
+// through unchanged.
+var textEncodings = map[string]encoding.Encoding{
+	"macroman":     charmap.Macintosh,
+	"utf-8":        nil,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"shift_jis":    japanese.ShiftJIS,
+}
+
+// textEncoding returns the text encoding for the current server.
+func (s *Session) textEncoding() encoding.Encoding {
+	name := defaultEncoding
+	if s.bookmark != nil && s.bookmark.Encoding != "" {
+		name = strings.ToLower(s.bookmark.Encoding)
+	}
+
+	enc, ok := textEncodings[name]
+	if !ok {
+		s.Logger.Warn("Unknown text encoding", "encoding", name)
+		enc = textEncodings[defaultEncoding]
+	}
+
+	return enc
+}
+
+// decodeText converts text received from the server to UTF-8.
+func (s *Session) decodeText(b []byte) string {
+	enc := s.textEncoding()
+	if enc == nil {
+		return string(b)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(decoded)
+}
+
+// encodeText converts UTF-8 text to the server's text encoding.  Characters the encoding can't represent are replaced.
+func (s *Session) encodeText(text string) []byte {
+	enc := s.textEncoding()
+	if enc == nil {
+		return []byte(text)
+	}
+
+	b, err := encoding.ReplaceUnsupported(enc.NewEncoder()).Bytes([]byte(text))
+	if err != nil {
+		return []byte(text)
+	}
+	return b
+}
+
+// nickColors is the palette that user names are colored from in chat.
+var nickColors = []tcell.Color{
+	tcell.ColorAqua,
+	tcell.ColorFuchsia,
+	tcell.ColorGreen,
+	tcell.ColorLightSkyBlue,
+	tcell.ColorOlive,
+	tcell.ColorOrange,
+	tcell.ColorPlum,
+	tcell.ColorTeal,
+	tcell.ColorYellow,
+	tcell.ColorLightCoral,
+	tcell.ColorLightGreen,
+	tcell.ColorMediumPurple,
+}
+
+// nickColor returns a color for the user name that stays the same across lines and sessions.
+func nickColor(name string) tcell.Color {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return nickColors[h.Sum32()%uint32(len(nickColors))]
+}
+
+// chatLine is a public or private chat line split into its parts.
+type chatLine struct {
+	Name  string
+	Text  string
+	Emote bool // Line is a "/me" style action
+}
+
+// parseChatLine splits a chat line into its sender and text.  Servers format chat lines as "\r%13.13s:  %s", or as
+// "\r*** %s %s" for emotes.
+func parseChatLine(msg string) (chatLine, bool) {
+	line := strings.TrimPrefix(msg, "\r")
+
+	if emote, ok := strings.CutPrefix(line, "*** "); ok {
+		name, text, found := strings.Cut(emote, " ")
+		return chatLine{Name: name, Text: text, Emote: true}, found
+	}
+
+	name, text, found := strings.Cut(line, ":  ")
+	if !found {
+		return chatLine{}, false
+	}
+
+	return chatLine{Name: strings.TrimLeft(name, " "), Text: text}, true
+}
+
+// formatChatLine formats a chat message for display in a chat TextView with a timestamp and colored sender name.  The
+// message text is escaped so that it can't inject color tags.
+func formatChatLine(t time.Time, msg string) string {
+	timestamp := fmt.Sprintf("[gray]%s[-]", t.Format("15:04"))
+
+	line, ok := parseChatLine(msg)
+	if !ok {
+		return fmt.Sprintf("%s %s", timestamp, tview.Escape(crToLF(strings.TrimPrefix(msg, "\r"))))
+	}
+
+	color := nickColor(line.Name)
+	if line.Emote {
+		return fmt.Sprintf("%s [%s]*** %s[-] %s", timestamp, color, tview.Escape(line.Name), tview.Escape(crToLF(line.Text)))
+	}
+
+	return fmt.Sprintf("%s [%s::b]%s[-::-]:  %s",
+		timestamp, color, tview.Escape(fmt.Sprintf("%13s", line.Name)), tview.Escape(crToLF(line.Text)),
+	)
+}
+
+// crToLF converts Hotline's carriage return line endings to newlines.
+func crToLF(s string) string {
+	return strings.ReplaceAll(s, "\r", "\n")
+}
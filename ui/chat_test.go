@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestParseChatLine(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want chatLine
+		ok   bool
+	}{
+		{
+			name: "padded name",
+			msg:  "\r         alice:  hello there",
+			want: chatLine{Name: "alice", Text: "hello there"},
+			ok:   true,
+		},
+		{
+			name: "text with separator",
+			msg:  "\rbob:  a:  b",
+			want: chatLine{Name: "bob", Text: "a:  b"},
+			ok:   true,
+		},
+		{
+			name: "emote",
+			msg:  "\r*** carol waves",
+			want: chatLine{Name: "carol", Text: "waves", Emote: true},
+			ok:   true,
+		},
+		{
+			name: "server message",
+			msg:  "\rWelcome to the server",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseChatLine(tt.msg)
+			if ok != tt.ok {
+				t.Fatalf("parseChatLine(%q) ok = %v, want %v", tt.msg, ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseChatLine(%q) = %+v, want %+v", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatName(t *testing.T) {
+	for name, want := range map[string]string{
+		"alice":            "alice",
+		"abcdefghijklmnop": "abcdefghijklm",
+		"Ünïcödé Üsernàmé": "Ünïcödé Üsern",
+	} {
+		if got := chatName(name); got != want {
+			t.Errorf("chatName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTextEncoding(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		encoded  []byte
+	}{
+		{encoding: "", text: "café", encoded: []byte{'c', 'a', 'f', 0x8e}},
+		{encoding: "MacRoman", text: "™", encoded: []byte{0xaa}},
+		{encoding: "utf-8", text: "café", encoded: []byte("café")},
+		{encoding: "windows-1252", text: "café", encoded: []byte{'c', 'a', 'f', 0xe9}},
+		{encoding: "shift_jis", text: "日本", encoded: []byte{0x93, 0xfa, 0x96, 0x7b}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			s := &Session{
+				Client:   &Client{Logger: slog.Default()},
+				bookmark: &Bookmark{Encoding: tt.encoding},
+			}
+
+			encoded := s.encodeText(tt.text)
+			if string(encoded) != string(tt.encoded) {
+				t.Errorf("encodeText(%q) = % x, want % x", tt.text, encoded, tt.encoded)
+			}
+			if decoded := s.decodeText(encoded); decoded != tt.text {
+				t.Errorf("decodeText(% x) = %q, want %q", encoded, decoded, tt.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package ui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// copyToClipboard copies text to the system clipboard using the OSC 52 terminal escape sequence, which is supported by
+// most modern terminal emulators and works over SSH.
+func copyToClipboard(text string) error {
+	_, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
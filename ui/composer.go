@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// largePasteLines is the number of lines above which pasted text is considered large enough to confirm before sending
+// to public chat.
+const largePasteLines = 5
+
+// Composer is a multi-line text editor for composing chat messages and news posts.
+//
+// In addition to the tview.TextArea key bindings (Ctrl-Left/Right or Alt-B/F to move by word, Ctrl-Z to undo and
+// Ctrl-Y to redo), Enter submits the text when a submit func is set.  Alt-Enter or Ctrl-J insert a newline instead.
+// Bracketed paste is supported when enabled on the Application, so pasted newlines are inserted rather than submitting
+// the message line by line.
+type Composer struct {
+	*tview.TextArea
+
+	submitFunc func(text string)
+	pasted     bool // Text was pasted since the last submit
+}
+
+func NewComposer() *Composer {
+	c := &Composer{
+		TextArea: tview.NewTextArea(),
+	}
+	c.TextArea.SetWordWrap(true)
+
+	return c
+}
+
+// SetSubmitFunc sets a handler that is called with the composer text when the user presses Enter.  Without a submit
+// func, Enter inserts a newline.
+func (c *Composer) SetSubmitFunc(handler func(text string)) *Composer {
+	c.submitFunc = handler
+	return c
+}
+
+// Pasted reports whether text was pasted into the composer since it was last cleared.
+func (c *Composer) Pasted() bool {
+	return c.pasted
+}
+
+// LineCount returns the number of lines of text in the composer.
+func (c *Composer) LineCount() int {
+	return strings.Count(c.GetText(), "\n") + 1
+}
+
+// HotlineText returns the composer text with line endings converted to the carriage returns used by Hotline.
+func (c *Composer) HotlineText() string {
+	return strings.ReplaceAll(c.GetText(), "\n", "\r")
+}
+
+// Clear empties the composer.
+func (c *Composer) Clear() {
+	c.SetText("", false)
+	c.pasted = false
+}
+
+// InputHandler intercepts Enter before passing the event on to the TextArea, which also applies any input capture.
+func (c *Composer) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		switch {
+		case event.Key() == tcell.KeyEnter && event.Modifiers()&tcell.ModAlt != 0,
+			event.Key() == tcell.KeyCtrlJ:
+			c.insert("\n")
+			return
+		case event.Key() == tcell.KeyEnter && c.submitFunc != nil:
+			if len(strings.TrimSpace(c.GetText())) > 0 {
+				c.submitFunc(c.GetText())
+			}
+			return
+		}
+
+		c.TextArea.InputHandler()(event, setFocus)
+	}
+}
+
+func (c *Composer) PasteHandler() func(pastedText string, setFocus func(p tview.Primitive)) {
+	return func(pastedText string, setFocus func(p tview.Primitive)) {
+		// Normalize Windows and classic Mac line endings.
+		pastedText = strings.ReplaceAll(pastedText, "\r\n", "\n")
+		pastedText = strings.ReplaceAll(pastedText, "\r", "\n")
+
+		c.pasted = true
+		c.TextArea.PasteHandler()(pastedText, setFocus)
+	}
+}
+
+// insert replaces the current selection, or inserts at the cursor if there is no selection.
+func (c *Composer) insert(text string) {
+	_, start, end := c.GetSelection()
+	c.Replace(start, end, text)
+}
@@ -0,0 +1,347 @@
+package ui
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dashboardRefreshInterval is how often the dashboard requests fresh client info for every user.
+const dashboardRefreshInterval = 30 * time.Second
+
+// Dashboard sort orders
+const (
+	dashboardSortJoined    = "joined"
+	dashboardSortName      = "name"
+	dashboardSortLogin     = "login"
+	dashboardSortTransfers = "transfers"
+)
+
+var dashboardSortOrders = []string{dashboardSortJoined, dashboardSortName, dashboardSortLogin, dashboardSortTransfers}
+
+// clientInfo is the client info text of a user parsed into its parts.  The format is the one used by Mobius and the
+// original Hotline server; lines not recognized are ignored.
+type clientInfo struct {
+	Login     string
+	Address   string
+	Downloads int // File and folder downloads in progress, including waiting downloads
+	Uploads   int // File and folder uploads in progress
+	Updated   time.Time
+}
+
+// parseClientInfo parses the client info text returned by TranGetClientInfoText.
+func parseClientInfo(info string) clientInfo {
+	ci := clientInfo{Updated: time.Now()}
+
+	var section string
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "Account:"):
+			ci.Login = strings.TrimSpace(strings.TrimPrefix(line, "Account:"))
+		case strings.HasPrefix(line, "Address:"):
+			ci.Address = strings.TrimSpace(strings.TrimPrefix(line, "Address:"))
+		case strings.HasPrefix(line, "--"):
+			section = strings.ToLower(strings.Trim(line, "- "))
+		case line == "" || line == "None.":
+		case strings.Contains(section, "download"):
+			ci.Downloads++
+		case strings.Contains(section, "upload"):
+			ci.Uploads++
+		}
+	}
+
+	return ci
+}
+
+// dashboard holds the state of the open admin dashboard.
+type dashboard struct {
+	table  *tview.Table
+	filter *tview.InputField
+	status *tview.TextView
+	sort   string
+	info   map[[2]byte]clientInfo
+	rows   []hotline.User // Users in the order they're shown in the table
+	stop   chan struct{}
+}
+
+// openDashboard shows the admin dashboard and starts refreshing client info for every user.
+func (s *Session) openDashboard() {
+	s.Pages.AddPage("dashboard", s.renderDashboard(), true, true)
+	s.refreshDashboardInfo()
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(dashboardRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.App.QueueUpdate(s.refreshDashboardInfo)
+			}
+		}
+	}(s.dashboard.stop)
+}
+
+func (s *Session) closeDashboard() {
+	if s.dashboard == nil {
+		return
+	}
+	close(s.dashboard.stop)
+	s.dashboard = nil
+	s.Pages.RemovePage("dashboard")
+}
+
+// refreshDashboardInfo requests the client info text for every user on the server.
+func (s *Session) refreshDashboardInfo() {
+	if s.dashboard == nil || !s.privileges.GetUserInfo {
+		return
+	}
+
+	for _, u := range s.UserList {
+		t := hotline.NewTransaction(hotline.TranGetClientInfoText, [2]byte{},
+			hotline.NewField(hotline.FieldUserID, u.ID[:]),
+		)
+		s.dashboardInfoRequests.add(t.ID, u)
+
+		if err := s.HLClient.Send(t); err != nil {
+			s.Logger.Error("Error requesting user info", "err", err)
+			return
+		}
+	}
+}
+
+// setDashboardStatus shows a message in the dashboard status line.
+func (s *Session) setDashboardStatus(format string, a ...any) {
+	s.dashboard.status.SetText(fmt.Sprintf("[gray]%s[-] %s", time.Now().Format("15:04:05"), tview.Escape(fmt.Sprintf(format, a...))))
+}
+
+func (s *Session) renderDashboard() *tview.Flex {
+	db := &dashboard{
+		table:  tview.NewTable().SetFixed(1, 0).SetSelectable(true, false),
+		filter: tview.NewInputField(),
+		status: tview.NewTextView().SetDynamicColors(true),
+		sort:   dashboardSortJoined,
+		info:   make(map[[2]byte]clientInfo),
+		stop:   make(chan struct{}),
+	}
+	s.dashboard = db
+
+	db.table.SetBorder(true)
+
+	db.filter.
+		SetLabel("/").
+		SetFieldBackgroundColor(tcell.ColorDimGray).
+		SetPlaceholder("filter by name, login or address").
+		SetChangedFunc(func(string) {
+			s.renderDashboardTable()
+		})
+	db.filter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			db.filter.SetText("")
+		}
+		s.App.SetFocus(db.table)
+	})
+
+	footer := tview.NewTextView().SetDynamicColors(true)
+	footer.SetText(strings.Join([]string{
+		shortcut("/", "Filter", true),
+		shortcut("s", "Sort", true),
+		shortcut("r", "Refresh", s.privileges.GetUserInfo),
+		shortcut("i", "Info", s.privileges.GetUserInfo),
+		shortcut("m", "Message", s.privileges.SendMessages),
+		shortcut("k", "Kick", s.privileges.DisconnectUsers),
+		shortcut("b", "Ban", s.privileges.DisconnectUsers),
+		shortcut("d", "Disconnect…", s.privileges.DisconnectUsers),
+		shortcut("Esc", "Close", true),
+	}, "  "))
+
+	// selected returns the user in the selected row.
+	selected := func() (hotline.User, bool) {
+		row, _ := db.table.GetSelection()
+		if row < 1 || row > len(db.rows) {
+			return hotline.User{}, false
+		}
+		return db.rows[row-1], true
+	}
+
+	db.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.closeDashboard()
+			return nil
+		}
+
+		switch event.Rune() {
+		case '/':
+			s.App.SetFocus(db.filter)
+			return nil
+		case 's':
+			i := slices.Index(dashboardSortOrders, db.sort)
+			db.sort = dashboardSortOrders[(i+1)%len(dashboardSortOrders)]
+			s.renderDashboardTable()
+			return nil
+		case 'r':
+			s.refreshDashboardInfo()
+			s.setDashboardStatus("Refreshing user info")
+			return nil
+		}
+
+		u, ok := selected()
+		if !ok {
+			return event
+		}
+
+		switch {
+		case event.Rune() == 'i' && s.privileges.GetUserInfo:
+			s.getUserInfo(u)
+		case event.Rune() == 'm' && s.privileges.SendMessages:
+			s.showSendMessage(u)
+		case event.Rune() == 'k' && s.privileges.DisconnectUsers:
+			if err := s.disconnectUser(u, 0, ""); err != nil {
+				s.Logger.Error("Error disconnecting user", "err", err)
+			}
+			s.setDashboardStatus("Disconnected %s", u.Name)
+		case event.Rune() == 'b' && s.privileges.DisconnectUsers:
+			if err := s.disconnectUser(u, 1, ""); err != nil {
+				s.Logger.Error("Error disconnecting user", "err", err)
+			}
+			s.setDashboardStatus("Disconnected and temporarily banned %s", u.Name)
+		case event.Rune() == 'd' && s.privileges.DisconnectUsers:
+			s.showDisconnectUser(u)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	s.renderDashboardTable()
+
+	dashboardPage := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(db.filter, 1, 0, false).
+		AddItem(db.table, 0, 1, true).
+		AddItem(db.status, 1, 0, false).
+		AddItem(footer, 1, 0, false)
+	dashboardPage.SetBorder(true).SetTitle(fmt.Sprintf("| Admin Dashboard: %s |", tview.Escape(s.ServerName)))
+
+	return dashboardPage
+}
+
+// userJoinedText formats the time the user joined the server.  Users already on the server when we connected show
+// the time we connected.
+func (s *Session) userJoinedText(u hotline.User) string {
+	if joined, ok := s.userJoined[u.ID]; ok {
+		return joined.Format("15:04")
+	}
+	return "<" + s.connectedAt.Format("15:04")
+}
+
+// renderDashboardTable redraws the dashboard table, preserving the selection.
+func (s *Session) renderDashboardTable() {
+	db := s.dashboard
+	if db == nil {
+		return
+	}
+
+	var selectedID [2]byte
+	if row, _ := db.table.GetSelection(); row >= 1 && row <= len(db.rows) {
+		selectedID = db.rows[row-1].ID
+	}
+
+	filter := strings.ToLower(db.filter.GetText())
+	db.rows = db.rows[:0]
+	for _, u := range s.UserList {
+		info := db.info[u.ID]
+		if filter == "" ||
+			strings.Contains(strings.ToLower(u.Name), filter) ||
+			strings.Contains(strings.ToLower(info.Login), filter) ||
+			strings.Contains(strings.ToLower(info.Address), filter) {
+			db.rows = append(db.rows, u)
+		}
+	}
+
+	joinedAt := func(u hotline.User) time.Time {
+		if joined, ok := s.userJoined[u.ID]; ok {
+			return joined
+		}
+		return s.connectedAt.Add(-time.Second)
+	}
+	switch db.sort {
+	case dashboardSortJoined:
+		slices.SortStableFunc(db.rows, func(a, b hotline.User) int {
+			return joinedAt(a).Compare(joinedAt(b))
+		})
+	case dashboardSortName:
+		slices.SortStableFunc(db.rows, func(a, b hotline.User) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	case dashboardSortLogin:
+		slices.SortStableFunc(db.rows, func(a, b hotline.User) int {
+			return cmp.Compare(strings.ToLower(db.info[a.ID].Login), strings.ToLower(db.info[b.ID].Login))
+		})
+	case dashboardSortTransfers:
+		slices.SortStableFunc(db.rows, func(a, b hotline.User) int {
+			ai, bi := db.info[a.ID], db.info[b.ID]
+			return cmp.Compare(bi.Downloads+bi.Uploads, ai.Downloads+ai.Uploads)
+		})
+	}
+
+	db.table.Clear()
+	for col, heading := range []string{"Name", "Login", "Address", "Joined", "DL", "UL", "Flags"} {
+		db.table.SetCell(0, col, tview.NewTableCell(heading).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	selectedRow := 1
+	for i, u := range db.rows {
+		row := i + 1
+		info, hasInfo := db.info[u.ID]
+
+		var flags []string
+		if userFlag(u, hotline.UserFlagAdmin) {
+			flags = append(flags, "admin")
+		}
+		if userFlag(u, hotline.UserFlagAway) {
+			flags = append(flags, "away")
+		}
+		if s.ignoredUsers[u.ID] {
+			flags = append(flags, "ignored")
+		}
+
+		login, address, downloads, uploads := "?", "?", "?", "?"
+		if hasInfo {
+			login, address = info.Login, info.Address
+			downloads, uploads = fmt.Sprint(info.Downloads), fmt.Sprint(info.Uploads)
+		}
+
+		nameColor := tcell.ColorWhite
+		if userFlag(u, hotline.UserFlagAdmin) {
+			nameColor = tcell.ColorRed
+		}
+
+		db.table.SetCell(row, 0, tview.NewTableCell(tview.Escape(u.Name)).SetTextColor(nameColor).SetExpansion(1))
+		db.table.SetCell(row, 1, tview.NewTableCell(tview.Escape(login)).SetExpansion(1))
+		db.table.SetCell(row, 2, tview.NewTableCell(tview.Escape(address)).SetExpansion(1))
+		db.table.SetCell(row, 3, tview.NewTableCell(s.userJoinedText(u)))
+		db.table.SetCell(row, 4, tview.NewTableCell(downloads).SetAlign(tview.AlignRight))
+		db.table.SetCell(row, 5, tview.NewTableCell(uploads).SetAlign(tview.AlignRight))
+		db.table.SetCell(row, 6, tview.NewTableCell(strings.Join(flags, ",")).SetTextColor(tcell.ColorGray))
+
+		if u.ID == selectedID {
+			selectedRow = row
+		}
+	}
+	db.table.Select(selectedRow, 0)
+
+	db.table.SetTitle(fmt.Sprintf("| Users (%d of %d) · %s |", len(db.rows), len(s.UserList), db.sort))
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io"
+	"sync"
+)
+
+// hlClient is the hotline client of a connection, made safe to send on from any goroutine.  hotline.Client.Send
+// records requests in a map that hotline.Client.HandleTransactions reads to find the type of their replies, neither of
+// them locked, and its writes to the connection aren't serialized.  The UI goroutine, the connection's goroutine and
+// the heartbeat all send, so hlClient locks sending and looks up replies itself.
+type hlClient struct {
+	*hotline.Client
+
+	sendMu sync.Mutex
+	sent   requests[[2]byte] // Types of the requests sent, to tell what their replies are
+}
+
+// Send sends a transaction, remembering the type of requests so their replies can be handled.
+func (c *hlClient) Send(t hotline.Transaction) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if t.IsReply == 0 {
+		c.sent.add(t.ID, t.Type)
+	}
+	if _, err := io.Copy(c.Connection, &t); err != nil {
+		return fmt.Errorf("error sending transaction: %w", err)
+	}
+
+	c.Logger.Debug("Sent Transaction", "IsReply", t.IsReply, "type", t.Type[:])
+	return nil
+}
+
+// HandleTransactions reads transactions from the server and calls their handlers, sending the transactions the
+// handlers return, until the connection closes.
+func (c *hlClient) HandleTransactions(ctx context.Context) error {
+	scanner := bufio.NewScanner(c.Connection)
+	scanner.Split(scanTransaction)
+
+	for scanner.Scan() {
+		// The scanner reuses its buffer, and the transaction keeps slices of what it's read from.
+		var t hotline.Transaction
+		if _, err := t.Write(bytes.Clone(scanner.Bytes())); err != nil {
+			return err
+		}
+
+		if t.IsReply == 1 {
+			typ, ok := c.sent.take(t.ID)
+			if !ok {
+				c.Logger.Warn("Reply to unknown transaction", "id", t.ID[:])
+				continue
+			}
+			t.Type = typ
+		}
+
+		handler, ok := c.Handlers[t.Type]
+		if !ok {
+			continue
+		}
+		c.Logger.Debug("Received transaction", "IsReply", t.IsReply, "type", t.Type[:])
+
+		res, err := handler(ctx, c.Client, &t)
+		if err != nil {
+			c.Logger.Error("Error handling transaction", "err", err)
+		}
+		for _, r := range res {
+			if err := c.Send(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// tranHeaderLen is the length of a transaction's header, which ends with the lengths of its fields.
+const tranHeaderLen = 20
+
+// scanTransaction is a bufio.SplitFunc that splits the data read from the server into transactions.
+func scanTransaction(data []byte, _ bool) (advance int, token []byte, err error) {
+	if len(data) < tranHeaderLen {
+		return 0, nil, nil
+	}
+
+	n := tranHeaderLen + int(binary.BigEndian.Uint32(data[12:16]))
+	if n > len(data) {
+		return 0, nil, nil
+	}
+	return n, data[:n], nil
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/jhalter/mobius/hotline"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHLClientReplies(t *testing.T) {
+	client, server := net.Pipe()
+	defer func() { _ = server.Close() }()
+
+	c := &hlClient{Client: hotline.NewClient("test", slog.New(slog.NewTextHandler(io.Discard, nil)))}
+	c.Connection = client
+
+	replies := make(chan hotline.Transaction, 1)
+	c.HandleFunc(hotline.TranGetClientInfoText, func(ctx context.Context, _ *hotline.Client, t *hotline.Transaction) ([]hotline.Transaction, error) {
+		replies <- *t
+		return []hotline.Transaction{hotline.NewTransaction(hotline.TranKeepAlive, [2]byte{})}, nil
+	})
+
+	handled := make(chan error, 1)
+	go func() { handled <- c.HandleTransactions(context.Background()) }()
+
+	scanner := bufio.NewScanner(server)
+	scanner.Split(scanTransaction)
+	receive := func() hotline.Transaction {
+		t.Helper()
+		if !scanner.Scan() {
+			t.Fatalf("no transaction sent: %v", scanner.Err())
+		}
+		var tran hotline.Transaction
+		if _, err := tran.Write(bytes.Clone(scanner.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		return tran
+	}
+
+	// Replies to unknown transactions are dropped, not handled as some other type.
+	unknown := hotline.Transaction{IsReply: 1, ID: [4]byte{9, 9, 9, 9}}
+	if _, err := io.Copy(server, &unknown); err != nil {
+		t.Fatal(err)
+	}
+
+	req := hotline.NewTransaction(hotline.TranGetClientInfoText, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, []byte{0, 1}),
+	)
+	go func() { _ = c.Send(req) }()
+	if got := receive(); got.ID != req.ID || got.Type != req.Type {
+		t.Fatalf("sent transaction %x of type %x, want %x of type %x", got.ID, got.Type, req.ID, req.Type)
+	}
+
+	reply := hotline.Transaction{IsReply: 1, ID: req.ID, Fields: []hotline.Field{
+		hotline.NewField(hotline.FieldData, []byte("info")),
+	}}
+	if _, err := io.Copy(server, &reply); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-replies:
+		if got.Type != hotline.TranGetClientInfoText || string(got.GetField(hotline.FieldData).Data) != "info" {
+			t.Errorf("handled reply %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reply wasn't handled")
+	}
+
+	if got := receive(); got.Type != hotline.TranKeepAlive {
+		t.Errorf("sent handler result of type %x, want %x", got.Type, hotline.TranKeepAlive)
+	}
+
+	_ = server.Close()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("HandleTransactions didn't return after the connection closed")
+	}
+}
@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"strconv"
+	"strings"
+)
+
+// IgnoreRule matches a user whose public chat should be hidden and whose private messages and chat invites should be
+// declined.  A rule matches by Name, by UserID, or both.
+type IgnoreRule struct {
+	Name   string `yaml:"Name,omitempty"`
+	UserID int    `yaml:"UserID,omitempty"`
+	Server string `yaml:"Server,omitempty"` // Address of the server the rule applies to; empty applies to all servers
+}
+
+func (r IgnoreRule) String() string {
+	var s string
+	switch {
+	case r.Name != "" && r.UserID != 0:
+		s = fmt.Sprintf("%s (ID %d)", r.Name, r.UserID)
+	case r.Name != "":
+		s = r.Name
+	default:
+		s = fmt.Sprintf("User ID %d", r.UserID)
+	}
+
+	return s
+}
+
+func (r IgnoreRule) appliesTo(server string) bool {
+	return r.Server == "" || r.Server == server
+}
+
+func (r IgnoreRule) matches(u hotline.User) bool {
+	if r.UserID != 0 && int(binary.BigEndian.Uint16(u.ID[:])) == r.UserID {
+		return true
+	}
+
+	return r.Name != "" && strings.EqualFold(r.Name, u.Name)
+}
+
+// chatName truncates a user name to the width used by servers when formatting public chat lines.  Servers count
+// MacRoman characters, which are one byte each, so the decoded name is truncated by characters rather than bytes.
+func chatName(name string) string {
+	const maxChatNameLen = 13
+	if runes := []rune(name); len(runes) > maxChatNameLen {
+		return string(runes[:maxChatNameLen])
+	}
+	return name
+}
+
+// isIgnored reports whether u matches an ignore rule for the current server.  The ignore rules and tracked users
+// belong to the UI goroutine, so transaction handlers check them in a queued update.
+func (s *Session) isIgnored(u hotline.User) bool {
+	if s.ignoredUsers[u.ID] {
+		return true
+	}
+
+	for _, rule := range s.Pref.Ignore {
+		if rule.appliesTo(s.serverAddr) && rule.matches(u) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIgnoredID reports whether the user with the given user ID is ignored.
+func (s *Session) isIgnoredID(id []byte) bool {
+	if len(id) != 2 {
+		return false
+	}
+
+	for _, u := range s.UserList {
+		if u.ID == [2]byte(id) {
+			return s.isIgnored(u)
+		}
+	}
+
+	return s.isIgnored(hotline.User{ID: [2]byte(id)})
+}
+
+// isIgnoredChatName reports whether a chat line sent under name should be hidden.  Servers truncate the name in chat
+// lines, so the comparison is made against the truncated form.
+func (s *Session) isIgnoredChatName(name string) bool {
+	for _, u := range s.UserList {
+		if chatName(u.Name) == name && s.isIgnored(u) {
+			return true
+		}
+	}
+
+	for _, rule := range s.Pref.Ignore {
+		if rule.Name != "" && rule.appliesTo(s.serverAddr) && strings.EqualFold(chatName(rule.Name), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackIgnoredUsers remembers the IDs of users in the user list that match an ignore rule so that they remain ignored
+// for the rest of the session if they change their name.
+func (s *Session) trackIgnoredUsers() {
+	if s.ignoredUsers == nil {
+		s.ignoredUsers = make(map[[2]byte]bool)
+	}
+
+	for _, u := range s.UserList {
+		if s.isIgnored(u) {
+			s.ignoredUsers[u.ID] = true
+		}
+	}
+}
+
+func (s *Session) renderIgnoreList() *tview.Flex {
+	ruleList := tview.NewList().ShowSecondaryText(true)
+	ruleList.SetBorder(true).SetTitle("| Ignored Users (Enter to remove) |")
+
+	var refreshRules func()
+	refreshRules = func() {
+		ruleList.Clear()
+		for i, rule := range s.Pref.Ignore {
+			scope := "All servers"
+			if rule.Server != "" {
+				scope = rule.Server
+			}
+			ruleList.AddItem(rule.String(), scope, 0, func() {
+				s.Pref.Ignore = append(s.Pref.Ignore[:i], s.Pref.Ignore[i+1:]...)
+				if err := s.savePrefs(); err != nil {
+					s.Logger.Error("Error saving ignore list", "err", err)
+				}
+
+				// Forget tracked users so that those no longer matching a rule become visible again.
+				s.ignoredUsers = nil
+				s.trackIgnoredUsers()
+
+				refreshRules()
+			})
+		}
+	}
+	refreshRules()
+
+	ignoreForm := tview.NewForm()
+	ignoreForm.
+		AddInputField("Name", "", 0, nil, nil).
+		AddInputField("User ID", "", 0, func(idStr string, _ rune) bool {
+			_, err := strconv.Atoi(idStr)
+			return err == nil
+		}, nil).
+		AddCheckbox("This server only", s.serverAddr != "", nil).
+		AddButton("Ignore", func() {
+			name := strings.TrimSpace(ignoreForm.GetFormItem(0).(*tview.InputField).GetText())
+			userID, _ := strconv.Atoi(ignoreForm.GetFormItem(1).(*tview.InputField).GetText())
+			if name == "" && userID == 0 {
+				return
+			}
+
+			rule := IgnoreRule{Name: name, UserID: userID}
+			if ignoreForm.GetFormItem(2).(*tview.Checkbox).IsChecked() {
+				rule.Server = s.serverAddr
+			}
+
+			s.Pref.Ignore = append(s.Pref.Ignore, rule)
+			if err := s.savePrefs(); err != nil {
+				s.Logger.Error("Error saving ignore list", "err", err)
+			}
+			s.trackIgnoredUsers()
+
+			ignoreForm.GetFormItem(0).(*tview.InputField).SetText("")
+			ignoreForm.GetFormItem(1).(*tview.InputField).SetText("")
+			refreshRules()
+		})
+	ignoreForm.SetBorder(true).SetTitle("| Add |")
+
+	ignorePage := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(ruleList, 0, 1, true).
+		AddItem(ignoreForm, 11, 0, false)
+	ignorePage.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			s.Pages.RemovePage("ignoreList")
+			return nil
+		case tcell.KeyTab:
+			if ruleList.HasFocus() {
+				s.App.SetFocus(ignoreForm)
+				return nil
+			}
+		case tcell.KeyBacktab:
+			if item, _ := ignoreForm.GetFocusedItemIndex(); item == 0 {
+				s.App.SetFocus(ruleList)
+				return nil
+			}
+		}
+		return event
+	})
+
+	centerFlex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(ignorePage, 24, 1, true).
+			AddItem(nil, 0, 1, false), 50, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	return centerFlex
+}
@@ -0,0 +1,575 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Layout of the bookmark files written by the classic Hotline clients (file type 'HTbm').  After the magic and
+// version come a reserved block and three fixed size Pascal strings in Mac OS Roman: the login, the password and the
+// server address.  The bookmark's name is the name of the file.
+const (
+	htbmMagic       = "HTsc"
+	htbmVersion     = 1
+	htbmReservedLen = 128
+	htbmLoginLen    = 32
+	htbmPasswordLen = 32
+	htbmAddrLen     = 256
+	htbmHeaderLen   = len(htbmMagic) + 2 + htbmReservedLen
+)
+
+// defaultImportGroup is the group imported bookmarks are put in unless the import names another.
+const defaultImportGroup = "Imported"
+
+// parseHTbm reads a classic Hotline bookmark file.
+func parseHTbm(name string, data []byte) (Bookmark, error) {
+	if len(data) < htbmHeaderLen+htbmLoginLen+htbmPasswordLen+htbmAddrLen || string(data[:4]) != htbmMagic {
+		return Bookmark{}, errors.New("not a Hotline bookmark file")
+	}
+	if v := binary.BigEndian.Uint16(data[4:6]); v != htbmVersion {
+		return Bookmark{}, fmt.Errorf("unsupported Hotline bookmark version %d", v)
+	}
+
+	fields := data[htbmHeaderLen:]
+	login := pascalString(fields[:htbmLoginLen])
+	password := pascalString(fields[htbmLoginLen : htbmLoginLen+htbmPasswordLen])
+	addr := pascalString(fields[htbmLoginLen+htbmPasswordLen:])
+
+	password, guessed := deobfuscateHTbmPassword(password)
+	b := Bookmark{
+		Name:            name,
+		Addr:            macRoman(addr),
+		Login:           macRoman(login),
+		Password:        macRoman(password),
+		passwordGuessed: guessed,
+	}
+	if b.Addr == "" {
+		return Bookmark{}, errors.New("bookmark has no server address")
+	}
+	return b, nil
+}
+
+// pascalString returns the string in a fixed size field that starts with its length.
+func pascalString(field []byte) []byte {
+	if len(field) == 0 {
+		return nil
+	}
+	n := min(int(field[0]), len(field)-1)
+	return field[1 : 1+n]
+}
+
+// deobfuscateHTbmPassword undoes the password obfuscation of the classic clients, which invert every byte the same
+// way the protocol does for logins.  Bookmarks saved by the earliest clients have the password in the clear, and the
+// file doesn't say which it is.  A password is only certain when undoing the obfuscation gives printable ASCII;
+// otherwise it's guessed: printable ASCII is taken to be in the clear, since inverting it never gives printable ASCII,
+// and anything else is taken to be obfuscated.
+func deobfuscateHTbmPassword(p []byte) (password []byte, guessed bool) {
+	if len(p) == 0 {
+		return p, false
+	}
+
+	deobfuscated := hotline.EncodeString(p)
+	if isPrintableASCII(deobfuscated) {
+		return deobfuscated, false
+	}
+	if isPrintableASCII(p) {
+		return p, true
+	}
+	return deobfuscated, true
+}
+
+func isPrintableASCII(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func macRoman(b []byte) string {
+	s, err := charmap.Macintosh.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return strings.TrimSpace(string(s))
+}
+
+// plistKeys maps the keys used by the bookmark exports of Heidrun, Frogblast and similar clients to bookmark fields.
+var plistKeys = map[string]string{
+	"name":         "name",
+	"title":        "name",
+	"bookmarkname": "name",
+	"address":      "addr",
+	"addr":         "addr",
+	"server":       "addr",
+	"host":         "addr",
+	"port":         "port",
+	"login":        "login",
+	"user":         "login",
+	"username":     "login",
+	"password":     "password",
+	"pass":         "password",
+}
+
+// parsePlistBookmarks reads a property list bookmark export.  Every dictionary with a server address is taken as a
+// bookmark, wherever it is nested, since the clients wrap their lists differently.
+func parsePlistBookmarks(data []byte) ([]Bookmark, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	var bookmarks []Bookmark
+	var dicts []map[string]string
+	var key string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse property list: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "dict":
+				dicts = append(dicts, make(map[string]string))
+				key = ""
+			case "key":
+				var k string
+				if err := dec.DecodeElement(&k, &t); err != nil {
+					return nil, err
+				}
+				key = strings.ToLower(strings.TrimSpace(k))
+			case "string", "integer":
+				var v string
+				if err := dec.DecodeElement(&v, &t); err != nil {
+					return nil, err
+				}
+				if field, ok := plistKeys[key]; ok && len(dicts) > 0 {
+					dicts[len(dicts)-1][field] = strings.TrimSpace(v)
+				}
+				key = ""
+			}
+		case xml.EndElement:
+			if t.Name.Local != "dict" || len(dicts) == 0 {
+				continue
+			}
+			d := dicts[len(dicts)-1]
+			dicts = dicts[:len(dicts)-1]
+
+			if d["addr"] == "" {
+				continue
+			}
+			addr := d["addr"]
+			if d["port"] != "" {
+				if host, _, err := net.SplitHostPort(addr); err == nil {
+					addr = host
+				}
+				addr = net.JoinHostPort(addr, d["port"])
+			}
+			bookmarks = append(bookmarks, Bookmark{
+				Name:     d["name"],
+				Addr:     addr,
+				Login:    d["login"],
+				Password: d["password"],
+			})
+		}
+	}
+
+	return bookmarks, nil
+}
+
+// csvColumns are the columns of a CSV bookmark file without a header row, in order.
+var csvColumns = []string{"name", "addr", "login", "password", "group"}
+
+// parseCSVBookmarks reads bookmarks from CSV.  A header row naming the columns is optional; without one the columns
+// are name, address, login, password and group, of which all but the address may be left off.  Lines starting with #
+// are ignored.
+func parseCSVBookmarks(data []byte) ([]Bookmark, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := csvColumns
+	if header, ok := csvHeader(records[0]); ok {
+		columns = header
+		records = records[1:]
+	}
+
+	var bookmarks []Bookmark
+	for _, rec := range records {
+		var b Bookmark
+		for i, v := range rec {
+			if i >= len(columns) {
+				break
+			}
+			v = strings.TrimSpace(v)
+			switch columns[i] {
+			case "name":
+				b.Name = v
+			case "addr":
+				b.Addr = v
+			case "login":
+				b.Login = v
+			case "password":
+				b.Password = v
+			case "group":
+				b.Group = v
+			}
+		}
+		if b.Addr != "" {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+
+	return bookmarks, nil
+}
+
+// csvHeader returns the bookmark fields named by a header row, or false if the row isn't a header.
+func csvHeader(row []string) ([]string, bool) {
+	columns := make([]string, len(row))
+	var hasAddr bool
+	for i, name := range row {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "group" || name == "folder" {
+			columns[i] = "group"
+			continue
+		}
+		columns[i] = plistKeys[name]
+		if columns[i] == "addr" {
+			hasAddr = true
+		}
+	}
+	return columns, hasAddr
+}
+
+// readBookmarkFile reads the bookmarks in a file, recognizing its format from its contents.
+func readBookmarkFile(path string) ([]Bookmark, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte(htbmMagic)):
+		b, err := parseHTbm(name, data)
+		if err != nil {
+			return nil, err
+		}
+		return []Bookmark{b}, nil
+	case bytes.HasPrefix(trimmed, []byte("<?xml")), bytes.HasPrefix(trimmed, []byte("<plist")), bytes.HasPrefix(trimmed, []byte("<!DOCTYPE plist")):
+		return parsePlistBookmarks(data)
+	default:
+		return parseCSVBookmarks(data)
+	}
+}
+
+// readBookmarks reads the bookmarks in a file, or in every bookmark file in a folder, like the Bookmarks folder of a
+// classic client.  Bookmarks in subfolders are put in groups named after them.  Files in a folder that aren't
+// bookmarks are skipped.
+func readBookmarks(path string) ([]Bookmark, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readBookmarkFile(path)
+	}
+
+	var bookmarks []Bookmark
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != path {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isBookmarkFile(p) {
+			return nil
+		}
+
+		found, err := readBookmarkFile(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		group, _ := filepath.Rel(path, filepath.Dir(p))
+		for i := range found {
+			if found[i].Group == "" && group != "." {
+				found[i].Group = filepath.ToSlash(group)
+			}
+		}
+		bookmarks = append(bookmarks, found...)
+		return nil
+	})
+
+	return bookmarks, err
+}
+
+// isBookmarkFile reports whether a file in a folder being imported looks like a bookmark file.
+func isBookmarkFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".plist", ".xml":
+		return true
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	magic := make([]byte, len(htbmMagic))
+	_, err = io.ReadFull(f, magic)
+	return err == nil && string(magic) == htbmMagic
+}
+
+// bookmarkKey identifies a bookmark for de-duplication: the same login on the same server.
+func bookmarkKey(b Bookmark) string {
+	login := b.Login
+	if login == "" {
+		login = hotline.GuestAccount
+	}
+	return strings.ToLower(withDefaultPort(b.Addr, b.TLS)) + "\x00" + login
+}
+
+// mergeBookmarks adds the bookmarks that aren't bookmarked yet, putting those without a group in group.  It returns
+// how many were added and how many were skipped as duplicates.
+func (cp *ClientPrefs) mergeBookmarks(imported []Bookmark, group string) (added, skipped int) {
+	seen := make(map[string]bool)
+	for _, b := range cp.Bookmarks {
+		seen[bookmarkKey(b)] = true
+	}
+
+	for _, b := range imported {
+		b.Addr = strings.TrimSpace(b.Addr)
+		if b.Login == "" {
+			b.Login = hotline.GuestAccount
+		}
+		if b.Name == "" {
+			b.Name = b.Addr
+		}
+		if b.Group == "" {
+			b.Group = group
+		}
+
+		key := bookmarkKey(b)
+		if seen[key] {
+			skipped++
+			continue
+		}
+		seen[key] = true
+
+		cp.Bookmarks = append(cp.Bookmarks, b)
+		added++
+	}
+
+	return added, skipped
+}
+
+// ImportResult is the outcome of importing bookmarks.
+type ImportResult struct {
+	Added            int // Bookmarks added
+	Duplicates       int // Bookmarks skipped because they were already bookmarked
+	SkippedPasswords int // Passwords left out of added bookmarks because the vault was locked
+
+	// Names of the added bookmarks whose passwords were saved but read from a format that doesn't say whether
+	// they're obfuscated, so they might be wrong
+	GuessedPasswords []string
+}
+
+// ImportBookmarks imports bookmarks from a file or folder of classic Hotline bookmark files, property list exports
+// or CSV, and saves them.  Imported passwords are only ever stored in the vault: if it's locked and the import has
+// passwords, passphrase is called to unlock it, or create it if creating is set.  The passwords are skipped if
+// passphrase is nil or returns "".
+func (mhc *Client) ImportBookmarks(path, group string, passphrase func(creating bool) (string, error)) (ImportResult, error) {
+	imported, err := readBookmarks(path)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	if hasPasswords(imported) && mhc.vault == nil && passphrase != nil {
+		pass, err := passphrase(!mhc.vaultExists())
+		if err != nil {
+			return ImportResult{}, err
+		}
+		if pass != "" {
+			if err := mhc.openOrCreateVault(pass); err != nil {
+				return ImportResult{}, fmt.Errorf("unlock vault: %w", err)
+			}
+		}
+	}
+
+	return mhc.importBookmarks(imported, group)
+}
+
+// hasPasswords reports whether any of the bookmarks has a plaintext password.
+func hasPasswords(bookmarks []Bookmark) bool {
+	return slices.ContainsFunc(bookmarks, func(b Bookmark) bool { return b.Password != "" })
+}
+
+// importBookmarks adds the imported bookmarks that aren't bookmarked yet and saves them.  Their passwords are moved
+// into the vault if it's unlocked, and dropped otherwise, so they never reach the config file.
+func (mhc *Client) importBookmarks(imported []Bookmark, group string) (ImportResult, error) {
+	var res ImportResult
+
+	before := len(mhc.Pref.Bookmarks)
+	res.Added, res.Duplicates = mhc.Pref.mergeBookmarks(imported, group)
+	added := mhc.Pref.Bookmarks[before:]
+
+	var stored int
+	for i := range added {
+		b := &added[i]
+		if b.Password == "" {
+			continue
+		}
+		if mhc.vault == nil {
+			b.Password = ""
+			res.SkippedPasswords++
+			continue
+		}
+
+		b.PasswordRef = newVaultRef()
+		mhc.vault.entries[b.PasswordRef] = b.Password
+		b.Password = ""
+		stored++
+		if b.passwordGuessed {
+			res.GuessedPasswords = append(res.GuessedPasswords, b.Name)
+		}
+	}
+
+	// The vault is written before the config, so an added bookmark never refers to a password that wasn't saved.
+	if stored > 0 {
+		if err := mhc.vault.save(); err != nil {
+			for _, b := range added {
+				delete(mhc.vault.entries, b.PasswordRef)
+			}
+			mhc.Pref.Bookmarks = mhc.Pref.Bookmarks[:before]
+			return ImportResult{}, fmt.Errorf("save vault: %w", err)
+		}
+	}
+
+	if res.Added > 0 {
+		mhc.bookmarksChanged()
+	}
+
+	return res, nil
+}
+
+// showImportBookmarks shows a form for importing bookmarks.
+func (mhc *Client) showImportBookmarks() {
+	form := tview.NewForm()
+	form.
+		AddInputField("File or Folder", "", 0, nil, nil).
+		AddInputField("Group", defaultImportGroup, 0, nil, nil)
+
+	// finish adds the bookmarks read from the file and shows how it went.
+	finish := func(imported []Bookmark, group string) {
+		res, err := mhc.importBookmarks(imported, group)
+		if err != nil {
+			mhc.Logger.Error("Error importing bookmarks", "err", err)
+			form.SetTitle(fmt.Sprintf("| %s |", err))
+			return
+		}
+		mhc.Pages.RemovePage("importBookmarks")
+
+		text := fmt.Sprintf("Imported %d bookmarks.\n%d were already bookmarked.", res.Added, res.Duplicates)
+		if res.SkippedPasswords > 0 {
+			text += fmt.Sprintf("\n%d passwords were left out.", res.SkippedPasswords)
+		}
+		if len(res.GuessedPasswords) > 0 {
+			text += fmt.Sprintf("\n\nThese passwords may be wrong, check them:\n%s", strings.Join(res.GuessedPasswords, ", "))
+		}
+		modal := tview.NewModal().
+			SetText(text).
+			AddButtons([]string{"OK"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				mhc.Pages.RemovePage("importedBookmarks")
+			})
+		mhc.Pages.AddPage("importedBookmarks", modal, false, true)
+	}
+
+	form.
+		AddButton("Import", func() {
+			path := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+			group := strings.TrimSpace(form.GetFormItem(1).(*tview.InputField).GetText())
+			if path == "" {
+				form.SetTitle("| File or folder is required |")
+				form.SetFocus(0)
+				return
+			}
+			if strings.HasPrefix(path, "~/") {
+				if home, err := os.UserHomeDir(); err == nil {
+					path = filepath.Join(home, path[2:])
+				}
+			}
+
+			imported, err := readBookmarks(path)
+			if err != nil {
+				mhc.Logger.Error("Error importing bookmarks", "path", path, "err", err)
+				form.SetTitle(fmt.Sprintf("| %s |", err))
+				return
+			}
+			if !hasPasswords(imported) || mhc.vault != nil {
+				finish(imported, group)
+				return
+			}
+
+			modal := tview.NewModal().
+				SetText("The imported bookmarks include passwords, which are only saved in the password vault.\n\nUnlock the vault to keep them?").
+				AddButtons([]string{"Leave Out Passwords", "Unlock Vault"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					mhc.Pages.RemovePage("importPasswords")
+					if buttonIndex == 1 {
+						mhc.unlockVault(func() { finish(imported, group) }, nil)
+						return
+					}
+					finish(imported, group)
+				})
+			mhc.Pages.AddPage("importPasswords", modal, false, true)
+		}).
+		AddButton("Cancel", func() {
+			mhc.Pages.RemovePage("importBookmarks")
+		})
+	form.SetBorder(true).SetTitle("| Import Bookmarks |")
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			mhc.Pages.RemovePage("importBookmarks")
+			return nil
+		}
+		return event
+	})
+
+	mhc.Pages.AddPage("importBookmarks", centered(form, 60, 9), true, true)
+}
@@ -0,0 +1,280 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// htbm builds a classic Hotline bookmark file.
+func htbm(login, password, addr []byte) []byte {
+	data := make([]byte, htbmHeaderLen+htbmLoginLen+htbmPasswordLen+htbmAddrLen)
+	copy(data, htbmMagic)
+	data[5] = htbmVersion
+
+	field := func(offset int, s []byte) {
+		data[offset] = byte(len(s))
+		copy(data[offset+1:], s)
+	}
+	field(htbmHeaderLen, login)
+	field(htbmHeaderLen+htbmLoginLen, password)
+	field(htbmHeaderLen+htbmLoginLen+htbmPasswordLen, addr)
+	return data
+}
+
+func invert(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = 255 - c
+	}
+	return out
+}
+
+func TestParseHTbm(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Bookmark
+	}{
+		{
+			name: "obfuscated password",
+			data: htbm([]byte("alice"), invert([]byte("hunter2")), []byte("hl.example.com:5500")),
+			want: Bookmark{Name: "Example", Addr: "hl.example.com:5500", Login: "alice", Password: "hunter2"},
+		},
+		{
+			name: "plain password",
+			data: htbm([]byte("bob"), []byte("secret"), []byte("10.0.0.1")),
+			want: Bookmark{Name: "Example", Addr: "10.0.0.1", Login: "bob", Password: "secret", passwordGuessed: true},
+		},
+		{
+			name: "Mac OS Roman login",
+			data: htbm([]byte{'c', 'a', 'f', 0x8e}, nil, []byte("hl.example.com")),
+			want: Bookmark{Name: "Example", Addr: "hl.example.com", Login: "café"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHTbm("Example", tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parseHTbm() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTbmInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"short":      []byte(htbmMagic),
+		"bad magic":  append([]byte("XXXX"), htbm(nil, nil, []byte("a"))[4:]...),
+		"no address": htbm([]byte("alice"), nil, nil),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseHTbm("Example", data); err == nil {
+				t.Error("parseHTbm() accepted an invalid bookmark")
+			}
+		})
+	}
+}
+
+func TestDeobfuscateHTbmPassword(t *testing.T) {
+	tests := []struct {
+		in          []byte
+		want        string
+		wantGuessed bool
+	}{
+		{in: invert([]byte("hunter2")), want: "hunter2"},
+		{in: []byte("hunter2"), want: "hunter2", wantGuessed: true},
+		{in: invert([]byte{'c', 'a', 'f', 0x8e}), want: "caf\x8e", wantGuessed: true},
+		{in: nil, want: ""},
+	}
+	for _, tt := range tests {
+		got, guessed := deobfuscateHTbmPassword(tt.in)
+		if string(got) != tt.want || guessed != tt.wantGuessed {
+			t.Errorf("deobfuscateHTbmPassword(% x) = %q, %v, want %q, %v", tt.in, got, guessed, tt.want, tt.wantGuessed)
+		}
+	}
+}
+
+func TestParsePlistBookmarks(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Bookmarks</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Example</string>
+			<key>Address</key><string>hl.example.com</string>
+			<key>Port</key><integer>5600</integer>
+			<key>Login</key><string>alice</string>
+			<key>Password</key><string>hunter2</string>
+		</dict>
+		<dict>
+			<key>Title</key><string>Other</string>
+			<key>Server</key><string>10.0.0.1:5500</string>
+		</dict>
+		<dict>
+			<key>Name</key><string>No address</string>
+		</dict>
+	</array>
+</dict>
+</plist>`)
+
+	got, err := parsePlistBookmarks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Bookmark{
+		{Name: "Example", Addr: "hl.example.com:5600", Login: "alice", Password: "hunter2"},
+		{Name: "Other", Addr: "10.0.0.1:5500"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsePlistBookmarks() returned %d bookmarks, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bookmark %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseCSVBookmarks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Bookmark
+	}{
+		{
+			name: "no header",
+			data: "# exported bookmarks\nExample, hl.example.com, alice, hunter2, Friends\nOther,10.0.0.1\n",
+			want: []Bookmark{
+				{Name: "Example", Addr: "hl.example.com", Login: "alice", Password: "hunter2", Group: "Friends"},
+				{Name: "Other", Addr: "10.0.0.1"},
+			},
+		},
+		{
+			name: "header",
+			data: "Address,Name,Folder\nhl.example.com,Example,Friends\n,Missing address,\n",
+			want: []Bookmark{
+				{Name: "Example", Addr: "hl.example.com", Group: "Friends"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSVBookmarks([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseCSVBookmarks() returned %d bookmarks, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("bookmark %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeBookmarks(t *testing.T) {
+	cp := &ClientPrefs{Bookmarks: []Bookmark{
+		{Name: "Existing", Addr: "hl.example.com", Login: "guest"},
+	}}
+	imported := []Bookmark{
+		{Name: "Same server", Addr: "HL.example.com:5500"},
+		{Name: "Other login", Addr: "hl.example.com", Login: "alice"},
+		{Addr: "10.0.0.1", Group: "Friends"},
+		{Name: "Repeated", Addr: "10.0.0.1:5500"},
+	}
+
+	added, skipped := cp.mergeBookmarks(imported, "Imported")
+	if added != 2 || skipped != 2 {
+		t.Errorf("mergeBookmarks() = %d added, %d skipped, want 2 added, 2 skipped", added, skipped)
+	}
+
+	want := []Bookmark{
+		{Name: "Existing", Addr: "hl.example.com", Login: "guest"},
+		{Name: "Other login", Addr: "hl.example.com", Login: "alice", Group: "Imported"},
+		{Name: "10.0.0.1", Addr: "10.0.0.1", Login: "guest", Group: "Friends"},
+	}
+	if len(cp.Bookmarks) != len(want) {
+		t.Fatalf("merged into %d bookmarks, want %d: %+v", len(cp.Bookmarks), len(want), cp.Bookmarks)
+	}
+	for i := range want {
+		if cp.Bookmarks[i] != want[i] {
+			t.Errorf("bookmark %d = %+v, want %+v", i, cp.Bookmarks[i], want[i])
+		}
+	}
+}
+
+func TestImportBookmarksPasswords(t *testing.T) {
+	imported := []Bookmark{
+		{Name: "Example", Addr: "hl.example.com", Login: "alice", Password: "hunter2"},
+		{Name: "Other", Addr: "10.0.0.1"},
+		{Name: "Guessed", Addr: "hl.example.net", Login: "bob", Password: "secret", passwordGuessed: true},
+	}
+
+	t.Run("locked vault", func(t *testing.T) {
+		mhc := &Client{CfgPath: filepath.Join(t.TempDir(), "client.yaml"), Pref: &ClientPrefs{}}
+
+		res, err := mhc.importBookmarks(imported, "Imported")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (ImportResult{Added: 3, SkippedPasswords: 2}); !reflect.DeepEqual(res, want) {
+			t.Errorf("importBookmarks() = %+v", res)
+		}
+		assertNoPlaintextPassword(t, mhc, "hunter2")
+	})
+
+	t.Run("unlocked vault", func(t *testing.T) {
+		mhc := &Client{CfgPath: filepath.Join(t.TempDir(), "client.yaml"), Pref: &ClientPrefs{}}
+		if err := mhc.openOrCreateVault("correct horse"); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := mhc.importBookmarks(imported, "Imported")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (ImportResult{Added: 3, GuessedPasswords: []string{"Guessed"}}); !reflect.DeepEqual(res, want) {
+			t.Errorf("importBookmarks() = %+v", res)
+		}
+		assertNoPlaintextPassword(t, mhc, "hunter2")
+
+		v, err := openVault(vaultPath(mhc.CfgPath), "correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref := mhc.Pref.Bookmarks[0].PasswordRef; v.entries[ref] != "hunter2" {
+			t.Errorf("vault entry %q = %q, want %q", ref, v.entries[ref], "hunter2")
+		}
+	})
+}
+
+// assertNoPlaintextPassword fails the test if the password is in a bookmark or the saved config.
+func assertNoPlaintextPassword(t *testing.T, mhc *Client, password string) {
+	t.Helper()
+
+	for _, b := range mhc.Pref.Bookmarks {
+		if b.Password != "" {
+			t.Errorf("bookmark %q has a plaintext password", b.Name)
+		}
+	}
+
+	data, err := os.ReadFile(mhc.CfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(password)) {
+		t.Error("config file contains the password")
+	}
+}
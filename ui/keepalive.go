@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"sync"
+	"time"
+)
+
+// keepaliveMaxMissed is how many keepalives may go unanswered before the connection is taken to be dead.  Allowing a
+// few keeps slow links and busy servers from being dropped for one late reply.
+const keepaliveMaxMissed = 3
+
+// keepaliveInterval returns how often to send TranKeepAlive, or 0 if keepalives are turned off.  They're off unless
+// the KeepaliveSeconds preference is set, since some servers never answer them.
+func (cp *ClientPrefs) keepaliveInterval() time.Duration {
+	if cp.KeepaliveSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cp.KeepaliveSeconds) * time.Second
+}
+
+// keepalives tracks the keepalives sent on a connection, which the heartbeat and transaction handling goroutines
+// both use.
+type keepalives struct {
+	mu      sync.Mutex
+	pending map[[4]byte]time.Time // When unanswered keepalives were sent, keyed by transaction ID
+	rtt     time.Duration         // Round-trip time of the last answered keepalive; 0 if none has been answered
+}
+
+// startKeepalives sets up keepalive tracking for a new connection, returning false if keepalives are turned off.  It
+// must be called before the connection's transactions are handled and its heartbeat is started.
+func (s *Session) startKeepalives() bool {
+	if s.Pref.keepaliveInterval() == 0 {
+		s.keepalives = nil
+		return false
+	}
+
+	s.keepalives = &keepalives{pending: make(map[[4]byte]time.Time)}
+	s.renderStatus()
+	return true
+}
+
+// heartbeat sends TranKeepAlive on the keepalive interval until done is closed, closing the connection once
+// keepaliveMaxMissed keepalives are unanswered.  This keeps NAT mappings alive and detects half-open connections,
+// which would otherwise go unnoticed until we send something.
+func (s *Session) heartbeat(c *hlClient, ka *keepalives, done <-chan struct{}) {
+	interval := s.Pref.keepaliveInterval()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		ka.mu.Lock()
+		missed := len(ka.pending)
+		ka.mu.Unlock()
+
+		if missed >= keepaliveMaxMissed {
+			s.Logger.Info("Keepalives unanswered; closing connection", "missed", missed, "interval", interval)
+			s.chatNotice("No reply from the server in %s", time.Duration(missed)*interval)
+			_ = c.Disconnect()
+			return
+		}
+
+		t := hotline.NewTransaction(hotline.TranKeepAlive, [2]byte{})
+		ka.mu.Lock()
+		ka.pending[t.ID] = time.Now()
+		ka.mu.Unlock()
+
+		if err := c.Send(t); err != nil {
+			s.Logger.Error("Error sending keepalive", "err", err)
+			_ = c.Disconnect()
+			return
+		}
+	}
+}
+
+// HandleKeepAlive handles keepalives from the server, which need no reply, and replies to our keepalives, which give
+// the round-trip time to the server.
+func (s *Session) HandleKeepAlive(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.IsReply != 1 || s.keepalives == nil {
+		return res, err
+	}
+
+	s.keepalives.mu.Lock()
+	sent, ok := s.keepalives.pending[t.ID]
+	if ok {
+		delete(s.keepalives.pending, t.ID)
+		s.keepalives.rtt = time.Since(sent)
+	}
+	s.keepalives.mu.Unlock()
+
+	if ok {
+		s.renderStatus()
+	}
+
+	return res, err
+}
+
+// renderStatus shows the round-trip time to the server in the server UI status area.
+func (s *Session) renderStatus() {
+	var rtt time.Duration
+	if s.keepalives != nil {
+		s.keepalives.mu.Lock()
+		rtt = s.keepalives.rtt
+		s.keepalives.mu.Unlock()
+	}
+
+	var status string
+	switch {
+	case s.keepalives == nil:
+	case rtt == 0:
+		status = "[gray]RTT –[-]"
+	case rtt < 200*time.Millisecond:
+		status = fmt.Sprintf("[gray]RTT[-] [green]%dms[-]", rtt.Milliseconds())
+	case rtt < time.Second:
+		status = fmt.Sprintf("[gray]RTT[-] [yellow]%dms[-]", rtt.Milliseconds())
+	default:
+		status = fmt.Sprintf("[gray]RTT[-] [red]%.1fs[-]", rtt.Seconds())
+	}
+
+	s.statusBar.SetText(status)
+	s.App.Draw()
+}
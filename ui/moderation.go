@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// Ban options for TranDisconnectUser
+var disconnectBans = []string{"No ban", "Temporary ban", "Permanent ban"}
+
+// showDisconnectUser shows a form for disconnecting a user, optionally banning them and telling them why.
+func (s *Session) showDisconnectUser(u hotline.User) {
+	disconnectForm := tview.NewForm()
+	disconnectForm.
+		AddInputField("Message", "", 0, nil, nil).
+		AddDropDown("Ban", disconnectBans, 0, nil).
+		AddButton("Disconnect", func() {
+			msg := strings.TrimSpace(disconnectForm.GetFormItem(0).(*tview.InputField).GetText())
+			ban, _ := disconnectForm.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+
+			if err := s.disconnectUser(u, ban, msg); err != nil {
+				s.Logger.Error("Error disconnecting user", "err", err)
+			}
+			s.Pages.RemovePage("disconnectUser")
+		}).
+		AddButton("Cancel", func() {
+			s.Pages.RemovePage("disconnectUser")
+		})
+	disconnectForm.SetBorder(true).SetTitle(fmt.Sprintf("| Disconnect %s |", tview.Escape(u.Name)))
+	disconnectForm.SetCancelFunc(func() {
+		s.Pages.RemovePage("disconnectUser")
+	})
+
+	s.Pages.AddPage("disconnectUser", centered(disconnectForm, 50, 9), true, true)
+}
+
+// disconnectUser disconnects the user from the server.  A ban of 1 bans their address temporarily and 2 permanently.
+// The message is sent with the disconnect, and the server shows it to the user.
+func (s *Session) disconnectUser(u hotline.User, ban int, msg string) error {
+	fields := []hotline.Field{
+		hotline.NewField(hotline.FieldUserID, u.ID[:]),
+	}
+	if ban > 0 {
+		fields = append(fields, hotline.NewField(hotline.FieldOptions, []byte{0, byte(ban)}))
+	}
+	if msg != "" {
+		fields = append(fields, hotline.NewField(hotline.FieldData, s.encodeText(msg)))
+	}
+
+	return s.HLClient.Send(hotline.NewTransaction(hotline.TranDisconnectUser, [2]byte{}, fields...))
+}
+
+// showBroadcast shows a composer for sending an admin message to every user on the server.
+func (s *Session) showBroadcast() {
+	msgInput := NewComposer()
+	msgInput.SetTextStyle(tcell.StyleDefault.Background(tcell.ColorDarkSlateGrey))
+	msgInput.SetBorder(true).SetTitle("| Broadcast to All Users |")
+	msgInput.SetSubmitFunc(func(string) {
+		msg := msgInput.HotlineText()
+		if strings.TrimSpace(msg) == "" {
+			return
+		}
+
+		t := hotline.NewTransaction(hotline.TranUserBroadcast, [2]byte{},
+			hotline.NewField(hotline.FieldData, s.encodeText(msg)),
+		)
+		if err := s.HLClient.Send(t); err != nil {
+			s.Logger.Error("Error sending broadcast", "err", err)
+		}
+		s.Pages.RemovePage("broadcast")
+	})
+	msgInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.Pages.RemovePage("broadcast")
+			return nil
+		}
+		return event
+	})
+
+	s.Pages.AddPage("broadcast", centered(msgInput, 60, 10), true, true)
+}
+
+// HandleDisconnectMsg handles the server disconnecting us with a message, such as when it's shutting down.  The
+// reason is shown once the connection has closed.
+func (s *Session) HandleDisconnectMsg(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	s.disconnectReason = crToLF(s.decodeText(t.GetField(hotline.FieldData).Data))
+	c.Logger.Info("Disconnected by server", "reason", s.disconnectReason)
+
+	return res, c.Disconnect()
+}
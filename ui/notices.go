@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"time"
+)
+
+// ChatNotices selects which changes to the user list are announced in chat.
+type ChatNotices struct {
+	Joins   bool `yaml:"Joins"`
+	Leaves  bool `yaml:"Leaves"`
+	Renames bool `yaml:"Renames"`
+	Icons   bool `yaml:"Icons"`
+	Away    bool `yaml:"Away"`
+}
+
+// defaultChatNotices is used when the config file has no ChatNotices.
+var defaultChatNotices = ChatNotices{
+	Joins:   true,
+	Leaves:  true,
+	Renames: true,
+}
+
+// chatNotices returns the chat notices enabled for the current server.
+func (s *Session) chatNotices() ChatNotices {
+	if s.bookmark != nil && s.bookmark.HideChatNotices {
+		return ChatNotices{}
+	}
+	if s.Pref.ChatNotices == nil {
+		return defaultChatNotices
+	}
+	return *s.Pref.ChatNotices
+}
+
+// chatNotice writes a client generated notice to the chat box.
+func (s *Session) chatNotice(format string, a ...any) {
+	_, _ = fmt.Fprintf(s.chatBox, "[gray]%s <<< %s >>>[-]\n", time.Now().Format("15:04"), tview.Escape(fmt.Sprintf(format, a...)))
+}
+
+// announceUserChange writes chat notices for the differences between the previous and current state of a user.
+func (s *Session) announceUserChange(prev, cur hotline.User) {
+	if s.isIgnored(cur) {
+		return
+	}
+
+	notices := s.chatNotices()
+
+	if notices.Renames && prev.Name != cur.Name {
+		s.chatNotice("%s is now known as %s", prev.Name, cur.Name)
+	}
+
+	if notices.Icons && !bytes.Equal(prev.Icon, cur.Icon) {
+		s.chatNotice("%s changed their icon", cur.Name)
+	}
+
+	if notices.Away && userFlag(prev, hotline.UserFlagAway) != userFlag(cur, hotline.UserFlagAway) {
+		if userFlag(cur, hotline.UserFlagAway) {
+			s.chatNotice("%s is away", cur.Name)
+		} else {
+			s.chatNotice("%s is back", cur.Name)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"time"
+)
+
+// privateChat holds the state and widgets of a private chat the user is a member of.
+type privateChat struct {
+	id      [4]byte
+	subject string
+	users   []hotline.User
+
+	layout    *tview.Flex
+	sessionID int // ID of the session the chat is on, to keep page names unique across servers
+	chatBox   *tview.TextView
+	userList  *tview.TextView
+	input     *Composer
+}
+
+func (pc *privateChat) pageName() string {
+	return fmt.Sprintf("privateChat%d-%x", pc.sessionID, pc.id)
+}
+
+func (pc *privateChat) title() string {
+	if pc.subject == "" {
+		return "| Private Chat |"
+	}
+	return fmt.Sprintf("| Private Chat: %s |", tview.Escape(pc.subject))
+}
+
+func (pc *privateChat) renderUserList() {
+	pc.userList.Clear()
+	for _, u := range pc.users {
+		_, _ = fmt.Fprintf(pc.userList, "%s\n", tview.Escape(u.Name))
+	}
+}
+
+// chatID returns the private chat ID field of the transaction.
+func chatID(t *hotline.Transaction) ([4]byte, bool) {
+	id := t.GetField(hotline.FieldChatID).Data
+	if len(id) != 4 {
+		return [4]byte{}, false
+	}
+	return [4]byte(id), true
+}
+
+// userID returns the user ID field of the transaction.
+func userID(t *hotline.Transaction) ([2]byte, bool) {
+	id := t.GetField(hotline.FieldUserID).Data
+	if len(id) != 2 {
+		return [2]byte{}, false
+	}
+	return [2]byte(id), true
+}
+
+// openPrivateChat shows the window for a private chat, creating it if needed.  Private chats belong to the UI
+// goroutine, so transaction handlers open them in a queued update.
+func (s *Session) openPrivateChat(id [4]byte, subject string, users []hotline.User) *privateChat {
+	if pc, ok := s.privateChats[id]; ok {
+		s.Pages.ShowPage(pc.pageName())
+		return pc
+	}
+
+	pc := &privateChat{
+		sessionID: s.id,
+		id:        id,
+		subject:   subject,
+		users:     users,
+	}
+
+	pc.chatBox = tview.NewTextView().
+		SetScrollable(true).
+		SetDynamicColors(true).
+		SetWordWrap(true).
+		SetChangedFunc(func() {
+			s.App.Draw()
+		})
+	pc.chatBox.SetBorder(true).SetTitle("| Chat |")
+
+	pc.userList = tview.NewTextView().SetDynamicColors(true)
+	pc.userList.SetBorder(true).SetTitle("Users")
+
+	pc.input = NewComposer()
+	pc.input.SetLabel("> ")
+	pc.input.SetTextStyle(tcell.StyleDefault.Background(tcell.ColorDimGray))
+	pc.input.SetBorder(true).SetTitle("Send")
+	pc.input.SetSubmitFunc(func(string) {
+		t := hotline.NewTransaction(hotline.TranChatSend, [2]byte{},
+			hotline.NewField(hotline.FieldChatID, pc.id[:]),
+			hotline.NewField(hotline.FieldData, s.encodeText(pc.input.HotlineText())),
+		)
+		if err := s.HLClient.Send(t); err != nil {
+			s.Logger.Error("Error sending private chat", "err", err)
+		}
+		pc.input.Clear()
+	})
+
+	pc.layout = tview.NewFlex().
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(pc.chatBox, 0, 1, false).
+			AddItem(pc.input, 3, 0, true), 0, 1, true).
+		AddItem(pc.userList, 25, 1, false)
+	pc.layout.SetBorder(true).SetTitle(pc.title()).SetTitleAlign(tview.AlignLeft)
+	pc.layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.confirmLeavePrivateChat(pc)
+			return nil
+		}
+		return event
+	})
+
+	pc.renderUserList()
+
+	s.privateChats[id] = pc
+	s.Pages.AddPage(pc.pageName(), pc.layout, true, true)
+	s.App.SetFocus(pc.input)
+
+	return pc
+}
+
+// confirmLeavePrivateChat asks whether to leave the private chat or only hide its window.
+func (s *Session) confirmLeavePrivateChat(pc *privateChat) {
+	modal := tview.NewModal().
+		SetText("Leave the private chat?").
+		AddButtons([]string{"Cancel", "Hide", "Leave"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			s.Pages.RemovePage("leaveChatModal")
+
+			switch buttonLabel {
+			case "Hide":
+				s.Pages.HidePage(pc.pageName())
+				s.App.SetFocus(s.chatInput)
+			case "Leave":
+				s.leavePrivateChat(pc)
+			default:
+				s.App.SetFocus(pc.input)
+			}
+		})
+
+	s.Pages.AddPage("leaveChatModal", modal, false, true)
+}
+
+func (s *Session) leavePrivateChat(pc *privateChat) {
+	t := hotline.NewTransaction(hotline.TranLeaveChat, [2]byte{},
+		hotline.NewField(hotline.FieldChatID, pc.id[:]),
+	)
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error leaving private chat", "err", err)
+	}
+
+	delete(s.privateChats, pc.id)
+	s.Pages.RemovePage(pc.pageName())
+	s.App.SetFocus(s.chatInput)
+}
+
+// inviteToPrivateChat starts a new private chat with the user.
+func (s *Session) inviteToPrivateChat(u hotline.User) {
+	t := hotline.NewTransaction(hotline.TranInviteNewChat, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, u.ID[:]),
+	)
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error sending private chat invite", "err", err)
+	}
+}
+
+// joinPrivateChat accepts an invitation to a private chat.  The chat window is opened when the server replies with the
+// chat subject and member list.
+func (s *Session) joinPrivateChat(id [4]byte) {
+	t := hotline.NewTransaction(hotline.TranJoinChat, [2]byte{},
+		hotline.NewField(hotline.FieldChatID, id[:]),
+	)
+	s.pendingChatJoins.add(t.ID, id)
+
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error joining private chat", "err", err)
+	}
+}
+
+// HandleInviteNewChat handles the reply to a private chat invite sent by us, which creates the chat.
+func (s *Session) HandleInviteNewChat(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+		return res, err
+	}
+
+	id, ok := chatID(t)
+	if !ok {
+		return res, errors.New("invalid chat ID")
+	}
+
+	selfID, ok := userID(t)
+	if !ok {
+		return res, errors.New("invalid user ID")
+	}
+
+	self := hotline.User{
+		ID:    selfID,
+		Name:  s.decodeText(t.GetField(hotline.FieldUserName).Data),
+		Icon:  t.GetField(hotline.FieldUserIconID).Data,
+		Flags: t.GetField(hotline.FieldUserFlags).Data,
+	}
+	s.App.QueueUpdateDraw(func() {
+		s.openPrivateChat(id, "", []hotline.User{self})
+	})
+
+	return res, err
+}
+
+// HandleInviteToChat handles invitations from other users to join a private chat.
+func (s *Session) HandleInviteToChat(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.IsReply == 1 {
+		return res, err
+	}
+
+	id, ok := chatID(t)
+	if !ok {
+		return res, errors.New("invalid chat ID")
+	}
+
+	fromID := t.GetField(hotline.FieldUserID).Data
+	fromName := s.decodeText(t.GetField(hotline.FieldUserName).Data)
+
+	s.App.QueueUpdateDraw(func() {
+		if s.isIgnoredID(fromID) {
+			s.Logger.Info("Declined private chat invite from ignored user", "name", fromName)
+			s.declineChatInvite(id)
+			return
+		}
+
+		pageName := fmt.Sprintf("chatInvite%x", id)
+		inviteModal := tview.NewModal().
+			SetText(fmt.Sprintf("%s has invited you to a private chat.", fromName)).
+			AddButtons([]string{"Decline", "Accept"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				s.Pages.RemovePage(pageName)
+
+				if buttonIndex == 1 {
+					s.joinPrivateChat(id)
+					return
+				}
+				s.declineChatInvite(id)
+			})
+		inviteModal.Box.SetTitle("Private Chat")
+
+		s.Pages.AddPage(pageName, inviteModal, false, true)
+	})
+
+	return res, err
+}
+
+// declineChatInvite declines an invitation to a private chat.
+func (s *Session) declineChatInvite(id [4]byte) {
+	t := hotline.NewTransaction(hotline.TranRejectChatInvite, [2]byte{},
+		hotline.NewField(hotline.FieldChatID, id[:]),
+	)
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error declining private chat invite", "err", err)
+	}
+}
+
+// HandleJoinChat handles the reply to joining a private chat, which contains the chat subject and its members.
+func (s *Session) HandleJoinChat(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	id, ok := s.pendingChatJoins.take(t.ID)
+	if !ok {
+		return res, err
+	}
+
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		errMsg := s.decodeText(t.GetField(hotline.FieldError).Data)
+		s.App.QueueUpdateDraw(func() {
+			// A chat that is already open is one we're rejoining after reconnecting.
+			if pc, rejoining := s.privateChats[id]; rejoining {
+				_, _ = fmt.Fprintf(pc.chatBox, "[gray]%s <<< Unable to rejoin the chat: %s >>>[-]\n",
+					time.Now().Format("15:04"), tview.Escape(errMsg),
+				)
+				return
+			}
+			s.addErrMsg(errMsg)
+		})
+		return res, err
+	}
+
+	var users []hotline.User
+	for _, field := range t.Fields {
+		if field.Type == hotline.FieldUsernameWithInfo {
+			var user hotline.User
+			if _, err := user.Write(field.Data); err != nil {
+				return res, fmt.Errorf("unable to read user data: %w", err)
+			}
+			user.Name = s.decodeText([]byte(user.Name))
+
+			users = append(users, user)
+		}
+	}
+
+	subject := s.decodeText(t.GetField(hotline.FieldChatSubject).Data)
+	s.App.QueueUpdateDraw(func() {
+		pc, rejoining := s.privateChats[id]
+		if !rejoining {
+			s.openPrivateChat(id, subject, users)
+			return
+		}
+
+		pc.subject = subject
+		pc.users = users
+		pc.layout.SetTitle(pc.title())
+		pc.renderUserList()
+		_, _ = fmt.Fprintf(pc.chatBox, "[gray]%s <<< Rejoined the chat >>>[-]\n", time.Now().Format("15:04"))
+	})
+
+	return res, err
+}
+
+// HandleNotifyChatChangeUser handles a user joining, or changing their name in, a private chat.
+func (s *Session) HandleNotifyChatChangeUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	id, _ := chatID(t)
+	newUserID, ok := userID(t)
+	if !ok {
+		return res, errors.New("invalid user ID")
+	}
+
+	newUser := hotline.User{
+		ID:    newUserID,
+		Name:  s.decodeText(t.GetField(hotline.FieldUserName).Data),
+		Icon:  t.GetField(hotline.FieldUserIconID).Data,
+		Flags: t.GetField(hotline.FieldUserFlags).Data,
+	}
+
+	s.App.QueueUpdateDraw(func() {
+		pc, ok := s.privateChats[id]
+		if !ok {
+			return
+		}
+
+		updatedUser := false
+		for i, u := range pc.users {
+			if u.ID == newUser.ID {
+				pc.users[i] = newUser
+				updatedUser = true
+			}
+		}
+		if !updatedUser {
+			pc.users = append(pc.users, newUser)
+			_, _ = fmt.Fprintf(pc.chatBox, "[gray]%s <<< %s has joined the chat >>>[-]\n",
+				time.Now().Format("15:04"), tview.Escape(newUser.Name),
+			)
+		}
+
+		pc.renderUserList()
+	})
+
+	return res, err
+}
+
+// HandleNotifyChatDeleteUser handles a user leaving a private chat.
+func (s *Session) HandleNotifyChatDeleteUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	id, _ := chatID(t)
+	exitUser := t.GetField(hotline.FieldUserID).Data
+
+	s.App.QueueUpdateDraw(func() {
+		pc, ok := s.privateChats[id]
+		if !ok {
+			return
+		}
+
+		var users []hotline.User
+		for _, u := range pc.users {
+			if string(u.ID[:]) == string(exitUser) {
+				_, _ = fmt.Fprintf(pc.chatBox, "[gray]%s <<< %s has left the chat >>>[-]\n",
+					time.Now().Format("15:04"), tview.Escape(u.Name),
+				)
+				continue
+			}
+			users = append(users, u)
+		}
+		pc.users = users
+
+		pc.renderUserList()
+	})
+
+	return res, err
+}
+
+// HandleNotifyChatSubject handles a change to the subject of a private chat.
+func (s *Session) HandleNotifyChatSubject(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	id, _ := chatID(t)
+	subject := s.decodeText(t.GetField(hotline.FieldChatSubject).Data)
+
+	s.App.QueueUpdateDraw(func() {
+		if pc, ok := s.privateChats[id]; ok {
+			pc.subject = subject
+			pc.layout.SetTitle(pc.title())
+		}
+	})
+
+	return res, err
+}
@@ -0,0 +1,242 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// Privileges is our account's access on the connected server, decoded from the access bitmap the server sends in
+// TranUserAccess.
+type Privileges struct {
+	Access hotline.AccessBitmap
+	Known  bool // The server has sent our access.  Until then everything is allowed and left to the server to refuse.
+
+	// Files
+	DownloadFiles   bool
+	DownloadFolders bool
+	UploadFiles     bool
+	UploadFolders   bool
+	UploadAnywhere  bool
+	DeleteFiles     bool
+	RenameFiles     bool
+	MoveFiles       bool
+	CreateFolders   bool
+	DeleteFolders   bool
+	RenameFolders   bool
+	MoveFolders     bool
+	CommentFiles    bool
+	CommentFolders  bool
+	ViewDropBoxes   bool
+	MakeAliases     bool
+
+	// Users
+	CreateAccounts    bool
+	DeleteAccounts    bool
+	OpenAccounts      bool
+	ModifyAccounts    bool
+	ChangeOwnPassword bool
+	DisconnectUsers   bool
+	CannotBeDiscon    bool
+	GetUserInfo       bool
+
+	// News
+	ReadNews           bool
+	PostNews           bool
+	DeleteNews         bool
+	CreateNewsCategory bool
+	DeleteNewsCategory bool
+	CreateNewsBundle   bool
+	DeleteNewsBundle   bool
+
+	// Chat and messaging
+	ReadChat     bool
+	SendChat     bool
+	OpenChat     bool
+	CloseChat    bool
+	ShowInList   bool
+	Broadcast    bool
+	SendMessages bool
+
+	// Misc
+	AnyName     bool
+	NoAgreement bool
+}
+
+// decodePrivileges decodes an access bitmap.
+func decodePrivileges(access hotline.AccessBitmap) Privileges {
+	return Privileges{
+		Access: access,
+		Known:  true,
+
+		DownloadFiles:   access.IsSet(hotline.AccessDownloadFile),
+		DownloadFolders: access.IsSet(hotline.AccessDownloadFolder),
+		UploadFiles:     access.IsSet(hotline.AccessUploadFile),
+		UploadFolders:   access.IsSet(hotline.AccessUploadFolder),
+		UploadAnywhere:  access.IsSet(hotline.AccessUploadAnywhere),
+		DeleteFiles:     access.IsSet(hotline.AccessDeleteFile),
+		RenameFiles:     access.IsSet(hotline.AccessRenameFile),
+		MoveFiles:       access.IsSet(hotline.AccessMoveFile),
+		CreateFolders:   access.IsSet(hotline.AccessCreateFolder),
+		DeleteFolders:   access.IsSet(hotline.AccessDeleteFolder),
+		RenameFolders:   access.IsSet(hotline.AccessRenameFolder),
+		MoveFolders:     access.IsSet(hotline.AccessMoveFolder),
+		CommentFiles:    access.IsSet(hotline.AccessSetFileComment),
+		CommentFolders:  access.IsSet(hotline.AccessSetFolderComment),
+		ViewDropBoxes:   access.IsSet(hotline.AccessViewDropBoxes),
+		MakeAliases:     access.IsSet(hotline.AccessMakeAlias),
+
+		CreateAccounts:    access.IsSet(hotline.AccessCreateUser),
+		DeleteAccounts:    access.IsSet(hotline.AccessDeleteUser),
+		OpenAccounts:      access.IsSet(hotline.AccessOpenUser),
+		ModifyAccounts:    access.IsSet(hotline.AccessModifyUser),
+		ChangeOwnPassword: access.IsSet(hotline.AccessChangeOwnPass),
+		DisconnectUsers:   access.IsSet(hotline.AccessDisconUser),
+		CannotBeDiscon:    access.IsSet(hotline.AccessCannotBeDiscon),
+		GetUserInfo:       access.IsSet(hotline.AccessGetClientInfo),
+
+		ReadNews:           access.IsSet(hotline.AccessNewsReadArt),
+		PostNews:           access.IsSet(hotline.AccessNewsPostArt),
+		DeleteNews:         access.IsSet(hotline.AccessNewsDeleteArt),
+		CreateNewsCategory: access.IsSet(hotline.AccessNewsCreateCat),
+		DeleteNewsCategory: access.IsSet(hotline.AccessNewsDeleteCat),
+		CreateNewsBundle:   access.IsSet(hotline.AccessNewsCreateFldr),
+		DeleteNewsBundle:   access.IsSet(hotline.AccessNewsDeleteFldr),
+
+		ReadChat:     access.IsSet(hotline.AccessReadChat),
+		SendChat:     access.IsSet(hotline.AccessSendChat),
+		OpenChat:     access.IsSet(hotline.AccessOpenChat),
+		CloseChat:    access.IsSet(hotline.AccessCloseChat),
+		ShowInList:   access.IsSet(hotline.AccessShowInList),
+		Broadcast:    access.IsSet(hotline.AccessBroadcast),
+		SendMessages: access.IsSet(hotline.AccessSendPrivMsg),
+
+		AnyName:     access.IsSet(hotline.AccessAnyName),
+		NoAgreement: access.IsSet(hotline.AccessNoAgreement),
+	}
+}
+
+// unknownPrivileges is used until the server sends our access, and allows everything.
+func unknownPrivileges() Privileges {
+	var access hotline.AccessBitmap
+	for i := range access {
+		access[i] = 0xFF
+	}
+
+	p := decodePrivileges(access)
+	p.Known = false
+	return p
+}
+
+// HandleClientTranUserAccess stores our access on the server, which is sent after login and again whenever an admin
+// changes our account.
+func (s *Session) HandleClientTranUserAccess(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	userAccess := t.GetField(hotline.FieldUserAccess).Data
+
+	var access hotline.AccessBitmap
+	copy(access[:], userAccess)
+	privileges := decodePrivileges(access)
+
+	s.App.QueueUpdateDraw(func() {
+		s.UserAccess = userAccess
+		s.privileges = privileges
+		s.applyPrivileges()
+	})
+
+	return res, err
+}
+
+// applyPrivileges updates the server UI to disable actions we lack the privileges for.
+func (s *Session) applyPrivileges() {
+	if s.commandList != nil {
+		s.commandList.SetText(s.shortcutText())
+	}
+
+	s.chatInput.SetDisabled(!s.privileges.SendChat)
+	if s.privileges.SendChat {
+		s.chatInput.SetPlaceholder("")
+	} else {
+		s.chatInput.SetPlaceholder("You are not allowed to send chat")
+	}
+}
+
+// shortcut formats a keyboard shortcut for a list of shortcuts, dimmed if the action isn't allowed.
+func shortcut(key, label string, allowed bool) string {
+	if !allowed {
+		return fmt.Sprintf("[gray]%s: %s[-]", key, label)
+	}
+	return fmt.Sprintf("[yellow]%s[-::]: %s", key, label)
+}
+
+// shortcutText returns the server UI keyboard shortcuts, with those we lack the privileges for dimmed.
+func (s *Session) shortcutText() string {
+	p := s.privileges
+	lines := [][]string{
+		{
+			shortcut("^n", "Read News", p.ReadNews),
+			shortcut("^p", "Post News", p.PostNews),
+			shortcut("^t", "Toggle Away", true),
+			shortcut("^o", "Options", true),
+		},
+		{
+			shortcut("^l", "View Logs", true),
+			shortcut("^f", "View Files", true),
+			shortcut("^k", "Ignore List", true),
+			shortcut("^g", "Accounts", p.OpenAccounts),
+		},
+		{
+			shortcut("^r", "Broadcast", p.Broadcast),
+			shortcut("^s", "Dashboard", p.GetUserInfo),
+			"[yellow]Tab[-::]: Users ([yellow]/[-::] Filter, [yellow]s[-::] Sort)",
+		},
+	}
+
+	var text []string
+	for _, line := range lines {
+		text = append(text, strings.Join(line, "   "))
+	}
+	return strings.Join(text, "\n")
+}
+
+// renderPrivileges renders the "My Privileges" page listing our access on the server.
+func (s *Session) renderPrivileges() *tview.Flex {
+	privilegeList := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	privilegeList.SetBorder(true).SetTitle("| My Privileges |")
+
+	var group string
+	for _, p := range accessPrivileges {
+		if p.Group != group {
+			if group != "" {
+				_, _ = fmt.Fprintln(privilegeList)
+			}
+			group = p.Group
+			_, _ = fmt.Fprintf(privilegeList, "[::b]%s[::-]\n", group)
+		}
+
+		if s.privileges.Access.IsSet(p.Bit) {
+			_, _ = fmt.Fprintf(privilegeList, "  [green]✓[-] %s\n", p.Name)
+		} else {
+			_, _ = fmt.Fprintf(privilegeList, "  [gray]✗ %s[-]\n", p.Name)
+		}
+	}
+	privilegeList.ScrollToBeginning()
+
+	if !s.privileges.Known {
+		privilegeList.SetText("The server hasn't sent your privileges.")
+	}
+
+	privilegeList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.Pages.RemovePage("privileges")
+			return nil
+		}
+		return event
+	})
+
+	return centered(privilegeList, 45, 30)
+}
@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyDirect as a bookmark's proxy connects to the server directly, bypassing the global proxy.
+const proxyDirect = "direct"
+
+// proxyFor returns the proxy URL for connecting to a server: the bookmark's if it sets one, or the global one.
+func (cp *ClientPrefs) proxyFor(b *Bookmark) string {
+	if b != nil && b.Proxy != "" {
+		if b.Proxy == proxyDirect {
+			return ""
+		}
+		return b.Proxy
+	}
+	return cp.Proxy
+}
+
+// dialTCP opens a TCP connection to addr, through the proxy if proxyURL is set.  Supported proxies are SOCKS5
+// (socks5:// or socks5h://, as opened by ssh -D) and HTTP proxies that allow CONNECT (http://), optionally with a
+// user name and password in the URL.  With socks5:// the host name is resolved locally and the proxy is given its
+// address; socks5h:// and http:// leave the lookup to the proxy.
+//
+// The proxy is used for server and tracker connections.  The client has no HTXF file transfers yet; when they're
+// added, their connections must be dialed with dialTCP too, or they'll bypass the proxy.
+func dialTCP(addr, proxyURL string) (net.Conn, error) {
+	if proxyURL == "" {
+		return net.DialTimeout("tcp", addr, dialTimeout)
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	if u.Scheme == "socks5" {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		addr = tcpAddr.String()
+	}
+
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to proxy: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, addr, u.User)
+	case "http":
+		err = httpConnect(conn, addr, u.User)
+	default:
+		err = fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// SOCKS5 protocol values from RFC 1928 and RFC 1929
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthNoAcceptable = 0xFF
+	socks5CmdConnect       = 0x01
+	socks5AddrIPv4         = 0x01
+	socks5AddrDomain       = 0x03
+	socks5AddrIPv6         = 0x04
+)
+
+var socks5Replies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect asks the SOCKS5 proxy on conn to connect to addr.  A host name is sent to the proxy unresolved, so
+// the DNS lookup happens on the far side of the proxy.
+func socks5Connect(conn net.Conn, addr string, user *url.Userinfo) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	method := byte(socks5AuthNone)
+	if user != nil {
+		method = socks5AuthPassword
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errors.New("not a SOCKS5 proxy")
+	}
+	if reply[1] == socks5AuthNoAcceptable || reply[1] != method {
+		return errors.New("proxy refused our authentication method")
+	}
+
+	if method == socks5AuthPassword {
+		password, _ := user.Password()
+		if len(user.Username()) > 255 || len(password) > 255 {
+			return errors.New("proxy user name or password too long")
+		}
+
+		auth := []byte{0x01, byte(len(user.Username()))}
+		auth = append(auth, user.Username()...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errors.New("proxy authentication failed")
+		}
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socks5AddrIPv4), ip4...)
+		} else {
+			req = append(append(req, socks5AddrIPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("host name too long")
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Reply: version, status, reserved, address type, then the bound address and port, which we don't need.
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0x00 {
+		if msg, ok := socks5Replies[head[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("connect failed with status %d", head[1])
+	}
+
+	var addrLen int
+	switch head[3] {
+	case socks5AddrIPv4:
+		addrLen = net.IPv4len
+	case socks5AddrIPv6:
+		addrLen = net.IPv6len
+	case socks5AddrDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		addrLen = int(l[0])
+	default:
+		return fmt.Errorf("unknown address type %d in reply", head[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, addrLen+2))
+	return err
+}
+
+// httpConnect asks the HTTP proxy on conn to open a tunnel to addr with the CONNECT method.
+func httpConnect(conn net.Conn, addr string, user *url.Userinfo) error {
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if user != nil {
+		password, _ := user.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + creds + "\r\n"
+	}
+	req += "\r\n"
+
+	if _, err := io.WriteString(conn, req); err != nil {
+		return err
+	}
+
+	// Read the response a byte at a time so nothing sent by the server after it is lost to buffering.
+	resp, err := http.ReadResponse(bufio.NewReader(byteReader{conn}), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// byteReader reads at most one byte at a time.
+type byteReader struct {
+	r io.Reader
+}
+
+func (br byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return br.r.Read(p)
+}
@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// socks5Request is a CONNECT request received by fakeSOCKS5.
+type socks5Request struct {
+	user, password string
+	addrType       byte
+	host           string
+	port           uint16
+}
+
+// fakeSOCKS5 serves a SOCKS5 handshake on conn, requiring a password if one is given, and answers the CONNECT request
+// with status.  The request is sent on the returned channel.
+func fakeSOCKS5(t *testing.T, conn net.Conn, password string, status byte) <-chan socks5Request {
+	t.Helper()
+
+	reqs := make(chan socks5Request, 1)
+	go func() {
+		defer close(reqs)
+		var req socks5Request
+
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		method := byte(socks5AuthNone)
+		if password != "" {
+			method = socks5AuthPassword
+		}
+		if greeting[2] != method {
+			_, _ = conn.Write([]byte{socks5Version, socks5AuthNoAcceptable})
+			return
+		}
+		_, _ = conn.Write([]byte{socks5Version, method})
+
+		if method == socks5AuthPassword {
+			r := bufio.NewReader(conn)
+			_, _ = r.ReadByte()
+			n, _ := r.ReadByte()
+			user := make([]byte, n)
+			_, _ = io.ReadFull(r, user)
+			n, _ = r.ReadByte()
+			pass := make([]byte, n)
+			_, _ = io.ReadFull(r, pass)
+			req.user, req.password = string(user), string(pass)
+
+			if req.password != password {
+				_, _ = conn.Write([]byte{0x01, 0x01})
+				return
+			}
+			_, _ = conn.Write([]byte{0x01, 0x00})
+		}
+
+		head := make([]byte, 4)
+		if _, err := io.ReadFull(conn, head); err != nil {
+			return
+		}
+		req.addrType = head[3]
+		switch req.addrType {
+		case socks5AddrIPv4, socks5AddrIPv6:
+			ip := make(net.IP, net.IPv4len)
+			if req.addrType == socks5AddrIPv6 {
+				ip = make(net.IP, net.IPv6len)
+			}
+			_, _ = io.ReadFull(conn, ip)
+			req.host = ip.String()
+		case socks5AddrDomain:
+			n := make([]byte, 1)
+			_, _ = io.ReadFull(conn, n)
+			host := make([]byte, n[0])
+			_, _ = io.ReadFull(conn, host)
+			req.host = string(host)
+		}
+		port := make([]byte, 2)
+		_, _ = io.ReadFull(conn, port)
+		req.port = binary.BigEndian.Uint16(port)
+
+		reqs <- req
+		_, _ = conn.Write([]byte{socks5Version, status, 0x00, socks5AddrIPv4, 127, 0, 0, 1, 0x15, 0x7c})
+	}()
+
+	return reqs
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		user     *url.Userinfo
+		password string // Password required by the proxy
+		status   byte
+		want     socks5Request
+		wantErr  bool
+	}{
+		{
+			name: "host name",
+			addr: "hl.example.com:5500",
+			want: socks5Request{addrType: socks5AddrDomain, host: "hl.example.com", port: 5500},
+		},
+		{
+			name: "IPv4",
+			addr: "192.0.2.1:5500",
+			want: socks5Request{addrType: socks5AddrIPv4, host: "192.0.2.1", port: 5500},
+		},
+		{
+			name: "IPv6",
+			addr: "[2001:db8::1]:5600",
+			want: socks5Request{addrType: socks5AddrIPv6, host: "2001:db8::1", port: 5600},
+		},
+		{
+			name:     "password",
+			addr:     "hl.example.com:5500",
+			user:     url.UserPassword("alice", "hunter2"),
+			password: "hunter2",
+			want:     socks5Request{user: "alice", password: "hunter2", addrType: socks5AddrDomain, host: "hl.example.com", port: 5500},
+		},
+		{
+			name:     "wrong password",
+			addr:     "hl.example.com:5500",
+			user:     url.UserPassword("alice", "wrong"),
+			password: "hunter2",
+			wantErr:  true,
+		},
+		{
+			name:     "password required",
+			addr:     "hl.example.com:5500",
+			password: "hunter2",
+			wantErr:  true,
+		},
+		{
+			name:    "connection refused",
+			addr:    "hl.example.com:5500",
+			status:  0x05,
+			want:    socks5Request{addrType: socks5AddrDomain, host: "hl.example.com", port: 5500},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxy := net.Pipe()
+			defer func() { _ = client.Close() }()
+			defer func() { _ = proxy.Close() }()
+
+			reqs := fakeSOCKS5(t, proxy, tt.password, tt.status)
+
+			err := socks5Connect(client, tt.addr, tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("socks5Connect() err = %v, want error %v", err, tt.wantErr)
+			}
+
+			_ = client.Close()
+			if got, ok := <-reqs; ok && got != tt.want {
+				t.Errorf("proxy received %+v, want %+v", got, tt.want)
+			} else if !ok && tt.want != (socks5Request{}) {
+				t.Errorf("proxy received no request, want %+v", tt.want)
+			}
+		})
+	}
+}
+
+func TestDialTCPSOCKS5Resolve(t *testing.T) {
+	for _, scheme := range []string{"socks5", "socks5h"} {
+		t.Run(scheme, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = ln.Close() }()
+
+			reqs := make(chan socks5Request, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				if req, ok := <-fakeSOCKS5(t, conn, "", 0x00); ok {
+					reqs <- req
+				}
+			}()
+
+			conn, err := dialTCP("localhost:5500", scheme+"://"+ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			// socks5:// resolves the host name itself, and socks5h:// leaves it to the proxy.
+			got := <-reqs
+			if resolved := got.addrType != socks5AddrDomain; resolved != (scheme == "socks5") {
+				t.Errorf("proxy received %q with address type %d", got.host, got.addrType)
+			}
+			if got.port != 5500 {
+				t.Errorf("proxy received port %d, want 5500", got.port)
+			}
+		})
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *url.Userinfo
+		response string
+		wantAuth string
+		wantErr  bool
+	}{
+		{
+			name:     "connected",
+			response: "HTTP/1.1 200 Connection established\r\n\r\nTRTP",
+		},
+		{
+			name:     "basic auth",
+			user:     url.UserPassword("alice", "hunter2"),
+			response: "HTTP/1.1 200 OK\r\n\r\nTRTP",
+			wantAuth: "Basic YWxpY2U6aHVudGVyMg==",
+		},
+		{
+			name:     "auth required",
+			response: "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxy := net.Pipe()
+			defer func() { _ = client.Close() }()
+			defer func() { _ = proxy.Close() }()
+
+			reqs := make(chan *http.Request, 1)
+			go func() {
+				defer close(reqs)
+				req, err := http.ReadRequest(bufio.NewReader(proxy))
+				if err != nil {
+					return
+				}
+				reqs <- req
+				_, _ = io.WriteString(proxy, tt.response)
+			}()
+
+			err := httpConnect(client, "hl.example.com:5500", tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("httpConnect() err = %v, want error %v", err, tt.wantErr)
+			}
+
+			req := <-reqs
+			if req == nil {
+				t.Fatal("proxy received no request")
+			}
+			if req.Method != http.MethodConnect || req.Host != "hl.example.com:5500" {
+				t.Errorf("proxy received %s %s, want CONNECT hl.example.com:5500", req.Method, req.Host)
+			}
+			if got := req.Header.Get("Proxy-Authorization"); got != tt.wantAuth {
+				t.Errorf("Proxy-Authorization = %q, want %q", got, tt.wantAuth)
+			}
+
+			// What the server sends after the proxy's response must be left for the client to read.
+			if !tt.wantErr {
+				got := make([]byte, 4)
+				if _, err := io.ReadFull(client, got); err != nil || !bytes.Equal(got, []byte("TRTP")) {
+					t.Errorf("read %q after the response, err %v, want %q", got, err, "TRTP")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Reconnect backoff: the delay before each attempt doubles from reconnectMinDelay up to reconnectMaxDelay, and is
+// jittered so clients dropped by the same network outage don't all retry at once.
+const (
+	reconnectMinDelay = 2 * time.Second
+	reconnectMaxDelay = 5 * time.Minute
+)
+
+// reconnectDelay returns how long to wait before reconnect attempt n, counting from 0.
+func reconnectDelay(n int) time.Duration {
+	delay := reconnectMaxDelay
+	if n < 16 && reconnectMinDelay<<n < reconnectMaxDelay {
+		delay = reconnectMinDelay << n
+	}
+
+	// Wait somewhere between half and all of the delay.
+	return delay/2 + rand.N(delay/2)
+}
+
+// shouldReconnect reports whether the session should reconnect after its connection closed.  We don't reconnect
+// after the user disconnected, the server refused our login, or the server disconnected us with a message, as it
+// does when an admin kicks us.  A connection that closes before we logged in is only retried while reconnecting, so a
+// server that never accepted us isn't retried forever.  It must be called on the UI goroutine.
+func (s *Session) shouldReconnect() bool {
+	return s.bookmark != nil && s.bookmark.AutoReconnect &&
+		!s.disconnecting && !s.loginRefused && s.disconnectReason == "" && (s.loggedIn || s.reconnecting)
+}
+
+// reconnect connects to the server again, retrying with backoff until it succeeds or the user disconnects.  It
+// returns false if the user disconnected before a connection was made.  The session stays reconnecting until the
+// server accepts our login, when rejoined is called.
+func (s *Session) reconnect() bool {
+	s.App.QueueUpdateDraw(func() {
+		s.reconnecting = true
+		s.renderTabs()
+	})
+
+	s.chatNotice("Connection to %s lost", s.ServerName)
+
+	for n := 0; ; n++ {
+		delay := reconnectDelay(n)
+		s.chatNotice("Reconnecting in %s (attempt %d)", delay.Round(time.Second), n+1)
+
+		select {
+		case <-s.stop:
+			return false
+		case <-time.After(delay):
+		}
+
+		c := s.newHLClient()
+		if err := s.connect(c); err != nil {
+			s.Logger.Error("Error reconnecting", "err", err)
+			s.chatNotice("Reconnect failed: %v", err)
+			continue
+		}
+
+		// The UI goroutine sends with the session's client, so the new one replaces it there, unless the user
+		// disconnected while we were connecting.
+		var disconnected bool
+		s.App.QueueUpdate(func() {
+			disconnected = s.disconnecting
+			if !disconnected {
+				s.HLClient = c
+				s.loggedIn = false
+			}
+		})
+		if disconnected {
+			_ = c.Disconnect()
+			return false
+		}
+
+		return true
+	}
+}
+
+// rejoined restores the session's state on the server after logging in again: our away status and options, and
+// membership of the private chats that were open.
+func (s *Session) rejoined() {
+	s.chatNotice("Reconnected to %s", s.ServerName)
+
+	s.reconnecting = false
+	s.renderTabs()
+
+	s.connectedAt = time.Now()
+	clear(s.userJoined)
+	s.userInfoRequests.reset()
+	s.dashboardInfoRequests.reset()
+	s.pendingChatJoins.reset()
+
+	if err := s.sendUserInfo(); err != nil {
+		s.Logger.Error("Error restoring user info", "err", err)
+	}
+
+	for id := range s.privateChats {
+		s.joinPrivateChat(id)
+	}
+}
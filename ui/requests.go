@@ -0,0 +1,40 @@
+package ui
+
+import "sync"
+
+// requests holds what we need to know to handle the replies to transactions we sent, keyed by transaction ID.
+// Requests are sent from the UI goroutine and their replies handled on the connection's goroutine, so access is
+// locked.  The zero value is ready to use.
+type requests[V any] struct {
+	mu      sync.Mutex
+	pending map[[4]byte]V
+}
+
+// add records a sent transaction.
+func (r *requests[V]) add(id [4]byte, v V) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == nil {
+		r.pending = make(map[[4]byte]V)
+	}
+	r.pending[id] = v
+}
+
+// take returns and forgets the request for a reply, or false if the reply isn't to one of these requests.
+func (r *requests[V]) take(id [4]byte) (V, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.pending[id]
+	delete(r.pending, id)
+	return v, ok
+}
+
+// reset forgets every request, such as after reconnecting, when no replies to them will come.
+func (r *requests[V]) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clear(r.pending)
+}
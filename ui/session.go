@@ -0,0 +1,370 @@
+package ui
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Session is a connection to a server along with its server UI and everything shown in it.  The embedded Client
+// holds what is shared by all sessions, such as the preferences and the tview application.
+type Session struct {
+	*Client
+
+	id       int // Identifies the session's pages
+	HLClient *hlClient
+
+	UserAccess []byte
+	privileges Privileges // Decoded UserAccess
+	filePath   []string   // Folder shown in the Files view
+	UserList   []hotline.User
+	shownUsers []hotline.User // Users in the order they're shown in userList
+	ServerName string
+	serverAddr string
+	login      string
+	password   string
+	useTLS     bool
+	bookmark   *Bookmark // Bookmark for the server, if there is one
+
+	// ignoredUsers holds the IDs of users on the server that matched an ignore rule.
+	ignoredUsers map[[2]byte]bool
+
+	privateChats     map[[4]byte]*privateChat
+	pendingChatJoins requests[[4]byte]      // Chat IDs of sent TranJoinChat transactions
+	userInfoRequests requests[hotline.User] // Users of sent TranGetClientInfoText transactions
+
+	accountAdmin *accountAdmin // State of the account administration page; nil when it isn't open
+
+	dashboard             *dashboard             // State of the admin dashboard; nil when it isn't open
+	dashboardInfoRequests requests[hotline.User] // Users of TranGetClientInfoText sent by the dashboard
+	connectedAt           time.Time              // When we connected to the server
+	userJoined            map[[2]byte]time.Time  // When users joined the server, for users who joined after us
+
+	away     bool
+	idleAway bool // Away status was set automatically after inactivity
+
+	unread int // Chat messages received while the session wasn't shown
+
+	disconnecting    bool          // We're closing the connection, so there's no need to tell the user it closed
+	disconnectReason string        // Message sent by the server with TranDisconnectMsg
+	stop             chan struct{} // Closed when the user disconnects, to stop reconnect attempts
+	loggedIn         bool          // The server accepted our login on the current connection
+	loginRefused     bool          // The server refused our login
+	agreed           bool          // We accepted the server agreement
+	reconnecting     bool          // The connection was lost and we're trying to connect again
+
+	openURL    *hotlineURL // Link to open once logged in
+	fileTarget string      // File or folder to select in the next file listing, when following a link
+
+	keepalives *keepalives // Keepalives sent on the current connection; nil if keepalives are turned off
+
+	tabBar      *tview.TextView
+	statusBar   *tview.TextView
+	chatBox     *tview.TextView
+	chatInput   *Composer
+	commandList *tview.TextView
+	userList    *tview.List
+	userFilter  *tview.InputField
+	userPane    *tview.Flex
+}
+
+// newSession creates a session for the server, with its own hotline client and widgets.
+func (mhc *Client) newSession(name, addr, login, password string, useTLS bool) *Session {
+	mhc.nextSessionID++
+
+	s := &Session{
+		Client:       mhc,
+		id:           mhc.nextSessionID,
+		ServerName:   name,
+		serverAddr:   addr,
+		login:        login,
+		password:     password,
+		useTLS:       useTLS,
+		bookmark:     mhc.Pref.bookmarkFor(addr),
+		privileges:   unknownPrivileges(),
+		privateChats: make(map[[4]byte]*privateChat),
+		connectedAt:  time.Now(),
+		userJoined:   make(map[[2]byte]time.Time),
+		stop:         make(chan struct{}),
+	}
+	s.HLClient = s.newHLClient()
+
+	s.tabBar = tview.NewTextView().SetDynamicColors(true)
+	s.statusBar = tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignRight)
+
+	s.chatBox = tview.NewTextView().
+		SetScrollable(true).
+		SetDynamicColors(true).
+		SetWordWrap(true).
+		SetChangedFunc(func() {
+			mhc.App.Draw() // TODO: docs say this is bad but it's the only way to show content during initial render??
+		})
+	s.chatBox.Box.SetBorder(true).SetTitle("| Chat |")
+
+	s.chatInput = NewComposer()
+	s.chatInput.
+		SetLabel("> ").
+		SetTextStyle(tcell.StyleDefault.Background(tcell.ColorDimGray))
+	s.chatInput.SetSubmitFunc(func(string) {
+		if s.chatInput.Pasted() && s.chatInput.LineCount() > largePasteLines {
+			s.confirmLargePaste()
+			return
+		}
+		s.sendChat()
+	})
+	s.chatInput.Box.SetBorder(true).SetTitle("Send")
+
+	s.userList = tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedFocusOnly(true)
+	s.userList.SetSelectedFunc(func(int, string, string, rune) {
+		if u, ok := s.selectedUser(); ok {
+			s.showUserMenu(u)
+		}
+	})
+	s.userPane = s.newUserPane()
+
+	return s
+}
+
+// username returns our name on the server: the bookmark's if it sets one, or the global one.
+func (s *Session) username() string {
+	if s.bookmark != nil && s.bookmark.Username != "" {
+		return s.bookmark.Username
+	}
+	return s.Pref.Username
+}
+
+// iconID returns our icon on the server: the bookmark's if it sets one, or the global one.
+func (s *Session) iconID() int {
+	if s.bookmark != nil && s.bookmark.IconID != 0 {
+		return s.bookmark.IconID
+	}
+	return s.Pref.IconID
+}
+
+// iconBytes returns iconID encoded for the icon field.
+func (s *Session) iconBytes() []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(s.iconID()))
+}
+
+// newHLClient returns a new hotline client for a connection to the server.
+func (s *Session) newHLClient() *hlClient {
+	c := &hlClient{Client: hotline.NewClient(s.username(), s.Logger.With("server", s.serverAddr))}
+	c.Pref.IconID = s.iconID()
+	c.Pref.EnableBell = s.Pref.EnableBell
+	s.registerHandlers(c)
+	return c
+}
+
+// connect connects the hotline client to the server and sends our login.  This is hotline.Client.Connect, but
+// dialing through the proxy and over TLS when the session uses them.
+func (s *Session) connect(c *hlClient) error {
+	var pin, caFile string
+	if s.bookmark != nil {
+		pin = s.bookmark.TLSFingerprint
+		caFile = s.bookmark.TLSCAFile
+	}
+	if caFile == "" {
+		caFile = s.Pref.TLSCAFile
+	}
+
+	conn, err := s.dial(s.serverAddr, s.Pref.proxyFor(s.bookmark), s.useTLS, pin, caFile)
+	if err != nil {
+		return err
+	}
+	c.Connection = conn
+
+	if err := c.Handshake(); err != nil {
+		return err
+	}
+
+	err = c.Send(hotline.NewTransaction(
+		hotline.TranLogin, [2]byte{0, 0},
+		hotline.NewField(hotline.FieldUserName, s.encodeText(s.username())),
+		hotline.NewField(hotline.FieldUserIconID, s.iconBytes()),
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(s.login))),
+		hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte(s.password))),
+	))
+	if err != nil {
+		return fmt.Errorf("error sending login transaction: %w", err)
+	}
+
+	return nil
+}
+
+// registerHandlers registers the handlers for transaction types that we should act on.
+func (s *Session) registerHandlers(c *hlClient) {
+	c.HandleFunc(hotline.TranChatMsg, s.HandleClientChatMsg)
+	c.HandleFunc(hotline.TranLogin, s.HandleClientTranLogin)
+	c.HandleFunc(hotline.TranShowAgreement, s.HandleClientTranShowAgreement)
+	c.HandleFunc(hotline.TranUserAccess, s.HandleClientTranUserAccess)
+	c.HandleFunc(hotline.TranGetUserNameList, s.HandleClientGetUserNameList)
+	c.HandleFunc(hotline.TranNotifyChangeUser, s.HandleNotifyChangeUser)
+	c.HandleFunc(hotline.TranNotifyDeleteUser, s.HandleNotifyDeleteUser)
+	c.HandleFunc(hotline.TranGetMsgs, s.TranGetMsgs)
+	c.HandleFunc(hotline.TranGetFileNameList, s.HandleGetFileNameList)
+	c.HandleFunc(hotline.TranServerMsg, s.HandleTranServerMsg)
+	c.HandleFunc(hotline.TranKeepAlive, s.HandleKeepAlive)
+	c.HandleFunc(hotline.TranInviteToChat, s.HandleInviteToChat)
+	c.HandleFunc(hotline.TranInviteNewChat, s.HandleInviteNewChat)
+	c.HandleFunc(hotline.TranJoinChat, s.HandleJoinChat)
+	c.HandleFunc(hotline.TranNotifyChatChangeUser, s.HandleNotifyChatChangeUser)
+	c.HandleFunc(hotline.TranNotifyChatDeleteUser, s.HandleNotifyChatDeleteUser)
+	c.HandleFunc(hotline.TranNotifyChatSubject, s.HandleNotifyChatSubject)
+	c.HandleFunc(hotline.TranGetClientInfoText, s.HandleGetClientInfoText)
+	c.HandleFunc(hotline.TranSendInstantMsg, s.HandleErrReply)
+	c.HandleFunc(hotline.TranDisconnectUser, s.HandleErrReply)
+	c.HandleFunc(hotline.TranUserBroadcast, s.HandleErrReply)
+	c.HandleFunc(hotline.TranDisconnectMsg, s.HandleDisconnectMsg)
+	c.HandleFunc(hotline.TranListUsers, s.HandleListUsers)
+	c.HandleFunc(hotline.TranGetUser, s.HandleGetUser)
+	c.HandleFunc(hotline.TranNewUser, s.HandleAccountReply)
+	c.HandleFunc(hotline.TranSetUser, s.HandleAccountReply)
+	c.HandleFunc(hotline.TranDeleteUser, s.HandleAccountReply)
+	c.HandleFunc(hotline.TranUpdateUser, s.HandleAccountReply)
+}
+
+// pageName returns the name of the session's server UI page.
+func (s *Session) pageName() string {
+	return fmt.Sprintf("%s%d", serverUIPage, s.id)
+}
+
+// disconnect closes the connection to the server.  The session is removed once its transaction handling stops.
+func (s *Session) disconnect() {
+	if !s.disconnecting {
+		close(s.stop)
+	}
+	s.disconnecting = true
+	_ = s.HLClient.Disconnect()
+}
+
+// removeSession removes a session whose connection has closed, switching to another server if it was shown.
+func (mhc *Client) removeSession(s *Session) {
+	i := slices.Index(mhc.sessions, s)
+	if i < 0 {
+		return
+	}
+	mhc.sessions = slices.Delete(mhc.sessions, i, i+1)
+
+	s.closeDashboard()
+	s.Pages.RemovePage(s.pageName())
+	for _, pc := range s.privateChats {
+		s.Pages.RemovePage(pc.pageName())
+	}
+
+	if mhc.current == s {
+		mhc.current = nil
+		if len(mhc.sessions) > 0 {
+			mhc.switchToSession(mhc.sessions[min(i, len(mhc.sessions)-1)])
+		} else {
+			mhc.Pages.SwitchToPage("home")
+		}
+	}
+	mhc.renderTabs()
+}
+
+// switchToSession shows the server UI of the session.
+func (mhc *Client) switchToSession(s *Session) {
+	if !s.Pages.HasPage(s.pageName()) {
+		// Login hasn't completed yet.
+		return
+	}
+
+	mhc.current = s
+	s.unread = 0
+
+	s.Pages.SwitchToPage(s.pageName())
+	s.App.SetFocus(s.chatInput)
+	mhc.renderTabs()
+}
+
+// cycleSession switches to the next (delta 1) or previous (delta -1) server.
+func (mhc *Client) cycleSession(delta int) {
+	if len(mhc.sessions) == 0 {
+		return
+	}
+
+	i := slices.Index(mhc.sessions, mhc.current)
+	i = (i + delta + len(mhc.sessions)) % len(mhc.sessions)
+	mhc.switchToSession(mhc.sessions[i])
+}
+
+// markUnread counts a message received while the session wasn't shown.
+func (s *Session) markUnread() {
+	if s.current == s {
+		if name, _ := s.Pages.GetFrontPage(); name != "home" {
+			return
+		}
+	}
+
+	s.unread++
+	s.renderTabs()
+}
+
+// renderTabs redraws the tab bar of every session.
+func (mhc *Client) renderTabs() {
+	var tabs []string
+	for i, s := range mhc.sessions {
+		label := fmt.Sprintf(" %d %s ", i+1, tview.Escape(s.ServerName))
+		if s.reconnecting {
+			label = fmt.Sprintf(" %d %s (reconnecting) ", i+1, tview.Escape(s.ServerName))
+		}
+		switch {
+		case s == mhc.current:
+			label = "[black:white]" + label + "[-:-]"
+		case s.unread > 0:
+			label = fmt.Sprintf("[yellow::b]%s(%d)[-::-] ", label, s.unread)
+		}
+		tabs = append(tabs, label)
+	}
+	text := strings.Join(tabs, "[gray]|[-]")
+
+	if len(mhc.sessions) > 1 {
+		text += "  [gray]Alt-1..9, Alt-←/→: Switch server[-]"
+	}
+
+	for _, s := range mhc.sessions {
+		s.tabBar.SetText(text)
+	}
+}
+
+// renderSessionList renders the list of connected servers shown from the home page.
+func (mhc *Client) renderSessionList() *tview.List {
+	list := tview.NewList()
+	list.Box.SetBorder(true).SetTitle("| Connected Servers |")
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			mhc.Pages.RemovePage("sessions")
+		}
+		return event
+	})
+
+	for i, s := range mhc.sessions {
+		secondary := s.serverAddr
+		if s.unread > 0 {
+			secondary = fmt.Sprintf("%s, %d unread", s.serverAddr, s.unread)
+		}
+		var shortcut rune
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		list.AddItem(s.ServerName, secondary, shortcut, func() {
+			mhc.Pages.RemovePage("sessions")
+			mhc.switchToSession(s)
+		})
+	}
+
+	if len(mhc.sessions) == 0 {
+		list.AddItem("Not connected to any servers", "", 0, func() {
+			mhc.Pages.RemovePage("sessions")
+		})
+	}
+
+	return list
+}
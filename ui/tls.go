@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/rivo/tview"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default ports used when an address doesn't include one.  Servers that terminate TLS conventionally listen 100 ports
+// above the plaintext port.
+const (
+	defaultPort    = "5500"
+	defaultTLSPort = "5600"
+)
+
+// dialTimeout limits how long connecting to a server or tracker, including the TLS handshake, may take.
+const dialTimeout = 5 * time.Second
+
+// untrustedCertError is returned when a server's certificate can't be verified and hasn't been pinned or trusted.
+type untrustedCertError struct {
+	addr        string
+	fingerprint string
+	err         error
+}
+
+func (e *untrustedCertError) Error() string {
+	return fmt.Sprintf("certificate of %s is not trusted: %v", e.addr, e.err)
+}
+
+// certFingerprint returns the SHA-256 fingerprint of the certificate as colon separated hex, like openssl shows it.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	hexPairs := make([]string, len(sum))
+	for i, b := range sum {
+		hexPairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hexPairs, ":")
+}
+
+// sameFingerprint compares two fingerprints, ignoring case and separators.
+func sameFingerprint(a, b string) bool {
+	normalize := strings.NewReplacer(":", "", " ", "")
+	return strings.EqualFold(normalize.Replace(a), normalize.Replace(b))
+}
+
+// certPool returns the system root certificates along with those in the CA bundle file, if one is set.
+func certPool(caFile string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if caFile == "" {
+		return pool, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+	}
+	return pool, nil
+}
+
+// tlsConfig returns the TLS config for connecting to addr.  A certificate matching the pinned fingerprint is accepted
+// as is; without a pin the certificate must verify against the system roots and CA bundle, or match the fingerprint
+// the user chose to trust on first use.
+func (mhc *Client) tlsConfig(addr, pin, caFile string) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := certPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := mhc.Pref.KnownCerts[addr]
+
+	return &tls.Config{
+		ServerName: host,
+		// Verification is done in VerifyConnection so that pinned and trusted certificates can be self-signed.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server sent no certificate")
+			}
+			leaf := cs.PeerCertificates[0]
+			fingerprint := certFingerprint(leaf)
+
+			switch {
+			case pin != "":
+				if !sameFingerprint(pin, fingerprint) {
+					return fmt.Errorf("certificate fingerprint %s doesn't match the pinned fingerprint", fingerprint)
+				}
+				return nil
+			case trusted != "":
+				if !sameFingerprint(trusted, fingerprint) {
+					return fmt.Errorf("certificate of %s has changed since you trusted it; new fingerprint %s", addr, fingerprint)
+				}
+				return nil
+			}
+
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := leaf.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				DNSName:       host,
+			})
+			if err != nil {
+				return &untrustedCertError{addr: addr, fingerprint: fingerprint, err: err}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// dial connects to a server or tracker, through the proxy if one is set and over TLS if useTLS is set.
+func (mhc *Client) dial(addr, proxyURL string, useTLS bool, pin, caFile string) (net.Conn, error) {
+	conn, err := dialTCP(addr, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if !useTLS {
+		return conn, nil
+	}
+
+	cfg, err := mhc.tlsConfig(addr, pin, caFile)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	_ = tlsConn.SetDeadline(time.Now().Add(dialTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = tlsConn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
+
+// confirmTrustCert asks whether to trust a certificate that couldn't be verified.  If the user trusts it, its
+// fingerprint is saved to the config and retry is called.  Each server gets its own prompt, so servers connected to
+// at the same time, like at startup, don't replace each other's.
+func (mhc *Client) confirmTrustCert(certErr *untrustedCertError, retry func()) {
+	pageName := "trustCert" + certErr.addr
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("The certificate of %s could not be verified:\n%v\n\nSHA-256 fingerprint:\n%s\n\nTrust this certificate?",
+			certErr.addr, certErr.err, certErr.fingerprint,
+		)).
+		AddButtons([]string{"Cancel", "Trust"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			mhc.Pages.RemovePage(pageName)
+			if buttonIndex != 1 {
+				return
+			}
+
+			if mhc.Pref.KnownCerts == nil {
+				mhc.Pref.KnownCerts = make(map[string]string)
+			}
+			mhc.Pref.KnownCerts[certErr.addr] = certErr.fingerprint
+			if err := mhc.savePrefs(); err != nil {
+				mhc.Logger.Error("Error saving trusted certificate", "err", err)
+			}
+
+			retry()
+		})
+	modal.Box.SetTitle("Untrusted Certificate")
+
+	mhc.Pages.AddPage(pageName, modal, false, true)
+}
@@ -0,0 +1,492 @@
+package ui
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// trackerTimeout limits how long fetching the listing from a tracker may take once connected.
+const trackerTimeout = 15 * time.Second
+
+// defaultTrackerPort is used when a tracker address doesn't include a port.
+const defaultTrackerPort = "5498"
+
+// trackerTLSPrefix marks a tracker in the tracker list as one to connect to over TLS.
+const trackerTLSPrefix = "tls://"
+
+// trackers returns the trackers to query.  Configs written before the tracker list existed have a single Tracker.
+func (cp *ClientPrefs) trackers() []string {
+	if len(cp.Trackers) > 0 {
+		return cp.Trackers
+	}
+	if cp.Tracker == "" {
+		return nil
+	}
+	return []string{cp.Tracker}
+}
+
+// parseTracker splits a tracker from the tracker list into its address, with the default port if it has none, and
+// whether to connect over TLS.
+func parseTracker(tracker string) (addr string, useTLS bool) {
+	addr, useTLS = strings.CutPrefix(strings.TrimSpace(tracker), trackerTLSPrefix)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultTrackerPort)
+	}
+	return addr, useTLS
+}
+
+// trackerServer is a server listed by one or more trackers.
+type trackerServer struct {
+	hotline.ServerRecord
+	trackers []string // Trackers listing the server
+}
+
+func (ts *trackerServer) users() int {
+	return int(binary.BigEndian.Uint16(ts.NumUsers[:]))
+}
+
+// probeResult is the outcome of connecting to a listed server.
+type probeResult struct {
+	latency time.Duration // Time taken to connect and complete the Hotline handshake
+	err     error
+}
+
+// Tracker listing sort orders
+const (
+	trackerSortTracker = "tracker" // Order the trackers listed the servers in
+	trackerSortUsers   = "users"
+	trackerSortName    = "name"
+	trackerSortAddress = "address"
+	trackerSortLatency = "latency"
+)
+
+var trackerSortOrders = []string{trackerSortTracker, trackerSortUsers, trackerSortName, trackerSortAddress, trackerSortLatency}
+
+// trackerPageSize is the number of servers shown on each page of the tracker listing.
+const trackerPageSize = 100
+
+// probeConcurrency limits how many servers are probed at once.
+const probeConcurrency = 16
+
+// trackerBrowser holds the state of the tracker listing while its trackers are queried.
+type trackerBrowser struct {
+	trackers []string
+	status   map[string]string // Result of the query to each tracker
+	servers  []*trackerServer  // Servers in the order they were first listed
+	byAddr   map[string]*trackerServer
+
+	sort   string
+	page   int
+	rows   []*trackerServer       // Servers on the shown page, in the order they're shown
+	probes map[string]probeResult // Results of probing servers, keyed by address
+	stop   chan struct{}          // Closed when the listing is closed, to stop probing
+	probed bool                   // Probing was started
+
+	table      *tview.Table
+	filter     *tview.InputField
+	statusView *tview.TextView
+}
+
+// queryTracker fetches the server listing from a tracker.
+func (mhc *Client) queryTracker(tracker string) ([]hotline.ServerRecord, error) {
+	addr, useTLS := parseTracker(tracker)
+
+	conn, err := mhc.dial(addr, mhc.Pref.Proxy, useTLS, "", mhc.Pref.TLSCAFile)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(trackerTimeout))
+
+	return hotline.GetListing(conn)
+}
+
+// probeServer connects to a server and completes the Hotline handshake, to tell whether it's reachable and how long
+// it takes.
+func (mhc *Client) probeServer(addr string) probeResult {
+	start := time.Now()
+
+	conn, err := dialTCP(addr, mhc.Pref.Proxy)
+	if err != nil {
+		return probeResult{err: err}
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if _, err := conn.Write(hotline.ClientHandshake); err != nil {
+		return probeResult{err: err}
+	}
+	reply := make([]byte, len(hotline.ServerHandshake))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return probeResult{err: err}
+	}
+	if !bytes.Equal(reply, hotline.ServerHandshake) {
+		return probeResult{err: errors.New("not a Hotline server")}
+	}
+
+	return probeResult{latency: time.Since(start)}
+}
+
+// closeTrackerBrowser closes the tracker listing, stopping any probes.
+func (mhc *Client) closeTrackerBrowser() {
+	if mhc.trackerBrowser == nil {
+		return
+	}
+	close(mhc.trackerBrowser.stop)
+	mhc.trackerBrowser = nil
+}
+
+// browseTracker queries every tracker concurrently and shows their merged listings as the results arrive.
+func (mhc *Client) browseTracker() {
+	trackers := mhc.Pref.trackers()
+	if len(trackers) == 0 {
+		errModal := tview.NewModal()
+		errModal.SetText("No trackers are configured.  Add them in Settings.")
+		errModal.AddButtons([]string{"Cancel"})
+		errModal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			mhc.Pages.RemovePage("errModal")
+		})
+		mhc.Pages.AddPage("errModal", errModal, false, true)
+		return
+	}
+
+	mhc.closeTrackerBrowser()
+	tb := &trackerBrowser{
+		trackers:   trackers,
+		status:     make(map[string]string),
+		byAddr:     make(map[string]*trackerServer),
+		sort:       trackerSortTracker,
+		probes:     make(map[string]probeResult),
+		stop:       make(chan struct{}),
+		table:      tview.NewTable().SetFixed(1, 0).SetSelectable(true, false),
+		filter:     tview.NewInputField(),
+		statusView: tview.NewTextView().SetDynamicColors(true),
+	}
+	mhc.trackerBrowser = tb
+
+	mhc.Pages.AddAndSwitchToPage(trackerListPage, mhc.renderTrackerBrowser(tb), true)
+
+	for _, tracker := range trackers {
+		tb.status[tracker] = "[yellow]querying…[-]"
+
+		go func() {
+			servers, err := mhc.queryTracker(tracker)
+			mhc.App.QueueUpdateDraw(func() {
+				mhc.addTrackerResults(tb, tracker, servers, err)
+			})
+		}()
+	}
+	mhc.renderTrackerTable(tb)
+}
+
+func (mhc *Client) renderTrackerBrowser(tb *trackerBrowser) *tview.Flex {
+	tb.table.SetBorder(true)
+	tb.statusView.SetBorder(true).SetTitle("| Trackers |")
+
+	tb.filter.
+		SetLabel("/").
+		SetFieldBackgroundColor(tcell.ColorDimGray).
+		SetPlaceholder("filter by name or description").
+		SetChangedFunc(func(string) {
+			tb.page = 0
+			mhc.renderTrackerTable(tb)
+		})
+	tb.filter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			tb.filter.SetText("")
+		}
+		mhc.App.SetFocus(tb.table)
+	})
+
+	footer := tview.NewTextView().SetDynamicColors(true)
+	footer.SetText(strings.Join([]string{
+		shortcut("Enter", "Connect", true),
+		shortcut("/", "Filter", true),
+		shortcut("s", "Sort", true),
+		shortcut("←/→", "Page", true),
+		shortcut("p", "Probe Servers", true),
+		shortcut("r", "Refresh", true),
+		shortcut("Esc", "Close", true),
+	}, "  "))
+
+	tb.table.SetSelectedFunc(func(row, _ int) {
+		if row < 1 || row > len(tb.rows) {
+			return
+		}
+		srv := tb.rows[row-1]
+
+		mhc.Pages.RemovePage("joinServer")
+
+		newJS := mhc.renderJoinServerForm(string(srv.Name), srv.Addr(), hotline.GuestAccount, "", trackerListPage, false, true)
+
+		mhc.Pages.AddPage("joinServer", newJS, true, true)
+		mhc.Pages.ShowPage("joinServer")
+	})
+
+	tb.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			mhc.closeTrackerBrowser()
+			mhc.Pages.SwitchToPage("home")
+			return nil
+		case tcell.KeyLeft:
+			if tb.page > 0 {
+				tb.page--
+				mhc.renderTrackerTable(tb)
+			}
+			return nil
+		case tcell.KeyRight:
+			tb.page++
+			mhc.renderTrackerTable(tb)
+			return nil
+		}
+
+		switch event.Rune() {
+		case '/':
+			mhc.App.SetFocus(tb.filter)
+			return nil
+		case 's':
+			i := slices.Index(trackerSortOrders, tb.sort)
+			tb.sort = trackerSortOrders[(i+1)%len(trackerSortOrders)]
+			mhc.renderTrackerTable(tb)
+			return nil
+		case 'p':
+			mhc.probeTrackerServers(tb)
+			return nil
+		case 'r':
+			mhc.browseTracker()
+			return nil
+		}
+		return event
+	})
+
+	return tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tb.filter, 1, 0, false).
+		AddItem(tb.table, 0, 1, true).
+		AddItem(tb.statusView, len(tb.trackers)+3, 0, false).
+		AddItem(footer, 1, 0, false)
+}
+
+// addTrackerResults merges the listing from a tracker into the tracker browser.
+func (mhc *Client) addTrackerResults(tb *trackerBrowser, tracker string, servers []hotline.ServerRecord, err error) {
+	if mhc.trackerBrowser != tb {
+		// The listing was closed or fetched again while the tracker was queried.
+		return
+	}
+
+	if err != nil {
+		mhc.Logger.Error("Error fetching tracker results", "tracker", tracker, "err", err)
+		tb.status[tracker] = fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error()))
+		mhc.renderTrackerTable(tb)
+
+		var certErr *untrustedCertError
+		if errors.As(err, &certErr) {
+			mhc.confirmTrustCert(certErr, mhc.browseTracker)
+		}
+		return
+	}
+
+	// Trackers can list a server more than once, such as when it re-registers with a new name, so servers are merged
+	// by address within a tracker's listing as well as across trackers.
+	listed := make(map[string]bool)
+	var added []*trackerServer
+	for _, srv := range servers {
+		if listed[srv.Addr()] {
+			continue
+		}
+		listed[srv.Addr()] = true
+
+		if ts, ok := tb.byAddr[srv.Addr()]; ok {
+			ts.trackers = append(ts.trackers, tracker)
+			continue
+		}
+
+		ts := &trackerServer{ServerRecord: srv, trackers: []string{tracker}}
+		tb.byAddr[srv.Addr()] = ts
+		tb.servers = append(tb.servers, ts)
+		added = append(added, ts)
+	}
+
+	tb.status[tracker] = fmt.Sprintf("[green]%d servers[-]", len(listed))
+
+	// Servers listed after probing started are probed too.
+	if tb.probed {
+		mhc.probeServers(tb, added)
+	}
+
+	mhc.renderTrackerTable(tb)
+}
+
+// probeTrackerServers starts probing every listed server in the background.
+func (mhc *Client) probeTrackerServers(tb *trackerBrowser) {
+	if tb.probed {
+		return
+	}
+	tb.probed = true
+	mhc.probeServers(tb, tb.servers)
+	mhc.renderTrackerTable(tb)
+}
+
+// probeServers probes the servers, at most probeConcurrency at a time, until the listing is closed.
+func (mhc *Client) probeServers(tb *trackerBrowser, servers []*trackerServer) {
+	addrs := make([]string, len(servers))
+	for i, srv := range servers {
+		addrs[i] = srv.Addr()
+	}
+
+	go func() {
+		sem := make(chan struct{}, probeConcurrency)
+		for _, addr := range addrs {
+			select {
+			case <-tb.stop:
+				return
+			case sem <- struct{}{}:
+			}
+
+			go func() {
+				defer func() { <-sem }()
+
+				result := mhc.probeServer(addr)
+				mhc.App.QueueUpdateDraw(func() {
+					if mhc.trackerBrowser != tb {
+						return
+					}
+					tb.probes[addr] = result
+					mhc.renderTrackerTable(tb)
+				})
+			}()
+		}
+	}()
+}
+
+// latencyText formats the result of probing a server.
+func latencyText(result probeResult, probed bool) string {
+	switch {
+	case !probed:
+		return ""
+	case result.err != nil:
+		return "[red]down[-]"
+	case result.latency < 200*time.Millisecond:
+		return fmt.Sprintf("[green]%dms[-]", result.latency.Milliseconds())
+	case result.latency < time.Second:
+		return fmt.Sprintf("[yellow]%dms[-]", result.latency.Milliseconds())
+	default:
+		return fmt.Sprintf("[red]%.1fs[-]", result.latency.Seconds())
+	}
+}
+
+// renderTrackerTable redraws the page of servers shown and the tracker status, preserving the selected server.
+func (mhc *Client) renderTrackerTable(tb *trackerBrowser) {
+	var selectedAddr string
+	if row, _ := tb.table.GetSelection(); row >= 1 && row <= len(tb.rows) {
+		selectedAddr = tb.rows[row-1].Addr()
+	}
+
+	filter := strings.ToLower(tb.filter.GetText())
+	var servers []*trackerServer
+	for _, srv := range tb.servers {
+		if filter == "" ||
+			strings.Contains(strings.ToLower(string(srv.Name)), filter) ||
+			strings.Contains(strings.ToLower(string(srv.Description)), filter) {
+			servers = append(servers, srv)
+		}
+	}
+
+	// latency orders reachable servers by latency, then unprobed servers, then unreachable ones.
+	latency := func(srv *trackerServer) time.Duration {
+		result, ok := tb.probes[srv.Addr()]
+		switch {
+		case !ok:
+			return time.Hour
+		case result.err != nil:
+			return 2 * time.Hour
+		}
+		return result.latency
+	}
+	switch tb.sort {
+	case trackerSortUsers:
+		slices.SortStableFunc(servers, func(a, b *trackerServer) int {
+			return cmp.Compare(b.users(), a.users())
+		})
+	case trackerSortName:
+		slices.SortStableFunc(servers, func(a, b *trackerServer) int {
+			return cmp.Compare(strings.ToLower(string(a.Name)), strings.ToLower(string(b.Name)))
+		})
+	case trackerSortAddress:
+		slices.SortStableFunc(servers, func(a, b *trackerServer) int {
+			if c := bytes.Compare(a.IPAddr[:], b.IPAddr[:]); c != 0 {
+				return c
+			}
+			return bytes.Compare(a.Port[:], b.Port[:])
+		})
+	case trackerSortLatency:
+		slices.SortStableFunc(servers, func(a, b *trackerServer) int {
+			return cmp.Compare(latency(a), latency(b))
+		})
+	}
+
+	pages := max(1, (len(servers)+trackerPageSize-1)/trackerPageSize)
+	tb.page = min(tb.page, pages-1)
+	start := tb.page * trackerPageSize
+	tb.rows = servers[start:min(start+trackerPageSize, len(servers))]
+
+	tb.table.Clear()
+	headings := []string{"Name", "Users", "Address", "Latency", "Description"}
+	if len(tb.trackers) > 1 {
+		headings = append(headings, "Trackers")
+	}
+	for col, heading := range headings {
+		tb.table.SetCell(0, col, tview.NewTableCell(heading).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	selectedRow := 1
+	for i, srv := range tb.rows {
+		row := i + 1
+		result, probed := tb.probes[srv.Addr()]
+
+		tb.table.SetCell(row, 0, tview.NewTableCell(tview.Escape(string(srv.Name))).SetMaxWidth(30))
+		tb.table.SetCell(row, 1, tview.NewTableCell(fmt.Sprint(srv.users())).SetAlign(tview.AlignRight))
+		tb.table.SetCell(row, 2, tview.NewTableCell(srv.Addr()))
+		tb.table.SetCell(row, 3, tview.NewTableCell(latencyText(result, probed)).SetAlign(tview.AlignRight))
+		tb.table.SetCell(row, 4, tview.NewTableCell(tview.Escape(string(srv.Description))).SetExpansion(1))
+		if len(tb.trackers) > 1 {
+			tb.table.SetCell(row, 5, tview.NewTableCell(tview.Escape(strings.Join(srv.trackers, ", "))).SetTextColor(tcell.ColorGray))
+		}
+
+		if srv.Addr() == selectedAddr {
+			selectedRow = row
+		}
+	}
+	tb.table.Select(selectedRow, 0)
+
+	tb.table.SetTitle(fmt.Sprintf("| Servers (%d of %d) · %s · page %d/%d |",
+		len(servers), len(tb.servers), tb.sort, tb.page+1, pages,
+	))
+
+	var status []string
+	for _, tracker := range tb.trackers {
+		status = append(status, fmt.Sprintf("%s  %s", tview.Escape(tracker), tb.status[tracker]))
+	}
+	if tb.probed {
+		var down int
+		for _, result := range tb.probes {
+			if result.err != nil {
+				down++
+			}
+		}
+		status = append(status, fmt.Sprintf("[gray]Probed %d of %d servers, %d unreachable[-]", len(tb.probes), len(tb.servers), down))
+	}
+	tb.statusView.SetText(strings.Join(status, "\n"))
+}
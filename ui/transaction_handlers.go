@@ -9,8 +9,7 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/jhalter/mobius/hotline"
 	"github.com/rivo/tview"
-	"math/big"
-	"strings"
+	"slices"
 	"time"
 )
 
@@ -30,43 +29,56 @@ import (
 //	},
 //}
 
-func (mhc *Client) HandleKeepAlive(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	return res, err
-}
+func (s *Session) HandleTranServerMsg(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	fromID := t.GetField(hotline.FieldUserID).Data
+	fromName := s.decodeText(t.GetField(hotline.FieldUserName).Data)
+	msg := crToLF(s.decodeText(t.GetField(hotline.FieldData).Data))
 
-func (mhc *Client) HandleTranServerMsg(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	now := time.Now().Format(time.RFC850)
+	s.App.QueueUpdateDraw(func() {
+		if s.isIgnoredID(fromID) {
+			c.Logger.Info("Declined private message from ignored user", "name", fromName)
+			return
+		}
 
-	msg := strings.ReplaceAll(string(t.GetField(hotline.FieldData).Data), "\r", "\n")
-	msg += "\n\nAt " + now
-	title := fmt.Sprintf("| Private Message From: 	%s |", t.GetField(hotline.FieldUserName).Data)
+		now := time.Now().Format(time.RFC850)
 
-	msgBox := tview.NewTextView().SetScrollable(true)
-	msgBox.SetText(msg).SetBackgroundColor(tcell.ColorDarkSlateBlue)
-	msgBox.SetTitle(title).SetBorder(true)
-	msgBox.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEscape:
-			mhc.Pages.RemovePage("serverMsgModal" + now)
-		}
-		return event
-	})
+		msg += "\n\nAt " + now
+		title := fmt.Sprintf("| Private Message From: 	%s |", tview.Escape(fromName))
 
-	centeredFlex := tview.NewFlex().
-		AddItem(nil, 0, 1, false).
-		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+		msgBox := tview.NewTextView().SetScrollable(true)
+		msgBox.SetText(msg).SetBackgroundColor(tcell.ColorDarkSlateBlue)
+		msgBox.SetTitle(title).SetBorder(true)
+		msgBox.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyEscape:
+				s.Pages.RemovePage("serverMsgModal" + now)
+			}
+			return event
+		})
+
+		centeredFlex := tview.NewFlex().
 			AddItem(nil, 0, 1, false).
-			AddItem(msgBox, 0, 2, true).
-			AddItem(nil, 0, 1, false), 0, 2, true).
-		AddItem(nil, 0, 1, false)
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(msgBox, 0, 2, true).
+				AddItem(nil, 0, 1, false), 0, 2, true).
+			AddItem(nil, 0, 1, false)
 
-	mhc.Pages.AddPage("serverMsgModal"+now, centeredFlex, true, true)
-	mhc.App.Draw() // TODO: errModal doesn't render without this.  wtf?
+		s.Pages.AddPage("serverMsgModal"+now, centeredFlex, true, true)
+	})
 
 	return res, err
 }
 
+// showErrMsg shows an error from the server.  It's for transaction handlers; the UI goroutine uses addErrMsg.
 func (mhc *Client) showErrMsg(msg string) {
+	mhc.App.QueueUpdateDraw(func() {
+		mhc.addErrMsg(msg)
+	})
+}
+
+// addErrMsg shows an error message.
+func (mhc *Client) addErrMsg(msg string) {
 	t := time.Now().Format(time.RFC850)
 
 	title := "| Error |"
@@ -91,12 +103,11 @@ func (mhc *Client) showErrMsg(msg string) {
 		AddItem(nil, 0, 1, false)
 
 	mhc.Pages.AddPage("serverMsgModal"+t, centeredFlex, true, true)
-	mhc.App.Draw() // TODO: errModal doesn't render without this.  wtf?
 }
 
-func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+func (s *Session) HandleGetFileNameList(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
 	if t.ErrorCode == [4]byte{0, 0, 0, 1} {
-		mhc.showErrMsg(string(t.GetField(hotline.FieldError).Data))
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
 		return res, err
 	}
 
@@ -105,19 +116,36 @@ func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client,
 	fTree.SetRoot(root).SetCurrentNode(root)
 	fTree.SetBorder(true).SetTitle("| Files |")
 	fTree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Copy a link to the selected file or folder, or to the folder shown
+		if event.Rune() == 'c' {
+			path, folder := s.filePath, true
+			if entry, ok := fTree.GetCurrentNode().GetReference().(*hotline.FileNameWithInfo); ok {
+				path = append(slices.Clone(s.filePath), s.decodeText(entry.Name))
+				folder = bytes.Equal(entry.Type[:], []byte("fldr"))
+			}
+
+			link := s.fileURL(path, folder)
+			if err := copyToClipboard(link); err != nil {
+				s.Logger.Error("Error copying link", "err", err)
+				return nil
+			}
+			fTree.SetTitle("| Files | Link copied |")
+			return nil
+		}
+
 		switch event.Key() {
 		case tcell.KeyEscape:
-			mhc.Pages.RemovePage("files")
-			mhc.filePath = []string{}
+			s.Pages.RemovePage("files")
+			s.filePath = []string{}
 		case tcell.KeyEnter:
 			selectedNode := fTree.GetCurrentNode()
 
 			if selectedNode.GetText() == "<- Back" {
-				mhc.filePath = mhc.filePath[:len(mhc.filePath)-1]
-				f := hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath(strings.Join(mhc.filePath, "/")))
+				s.filePath = s.filePath[:len(s.filePath)-1]
+				f := s.filePathField(s.filePath)
 
-				if err := mhc.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, f)); err != nil {
-					mhc.HLClient.Logger.Error("err", "err", err)
+				if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, f)); err != nil {
+					s.HLClient.Logger.Error("err", "err", err)
 				}
 				return event
 			}
@@ -125,13 +153,14 @@ func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client,
 			entry := selectedNode.GetReference().(*hotline.FileNameWithInfo)
 
 			if bytes.Equal(entry.Type[:], []byte("fldr")) {
-				c.Logger.Info("get new directory listing", "name", string(entry.Name))
+				name := s.decodeText(entry.Name)
+				c.Logger.Info("get new directory listing", "name", name)
 
-				mhc.filePath = append(mhc.filePath, string(entry.Name))
-				f := hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath(strings.Join(mhc.filePath, "/")))
+				s.filePath = append(s.filePath, name)
+				f := s.filePathField(s.filePath)
 
-				if err := mhc.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, f)); err != nil {
-					mhc.HLClient.Logger.Error("err", "err", err)
+				if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, f)); err != nil {
+					s.HLClient.Logger.Error("err", "err", err)
 				}
 			} else {
 				// TODO: initiate file download
@@ -142,11 +171,16 @@ func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client,
 		return event
 	})
 
-	if len(mhc.filePath) > 0 {
+	if len(s.filePath) > 0 {
 		node := tview.NewTreeNode("<- Back")
 		root.AddChild(node)
 	}
 
+	// File or folder named by a link being followed
+	target := s.fileTarget
+	s.fileTarget = ""
+	var targetNode *tview.TreeNode
+
 	for _, f := range t.Fields {
 		var fn hotline.FileNameWithInfo
 		_, err = fn.Write(f.Data)
@@ -154,17 +188,40 @@ func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client,
 			return nil, nil
 		}
 
+		name := s.decodeText(fn.Name)
+		var node *tview.TreeNode
 		if bytes.Equal(fn.Type[:], []byte("fldr")) {
-			node := tview.NewTreeNode(fmt.Sprintf("[blue::]📁 %s[-:-:-]", fn.Name))
+			node = tview.NewTreeNode(fmt.Sprintf("[blue::]📁 %s[-:-:-]", name))
 			node.SetReference(&fn)
 			root.AddChild(node)
 		} else {
 			size := binary.BigEndian.Uint32(fn.FileSize[:]) / 1024
 
-			node := tview.NewTreeNode(fmt.Sprintf("   %-40s %10v KB", fn.Name, size))
+			node = tview.NewTreeNode(fmt.Sprintf("   %-40s %10v KB", name, size))
 			node.SetReference(&fn)
 			root.AddChild(node)
 		}
+		if target != "" && name == target {
+			targetNode = node
+		}
+	}
+
+	if target != "" && targetNode == nil {
+		fTree.SetTitle(fmt.Sprintf("| Files | %s not found |", tview.Escape(target)))
+	}
+	if targetNode != nil {
+		entry := targetNode.GetReference().(*hotline.FileNameWithInfo)
+		if bytes.Equal(entry.Type[:], []byte("fldr")) {
+			// The link names a folder, so list it instead.
+			s.filePath = append(s.filePath, target)
+			f := s.filePathField(s.filePath)
+			if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, f)); err != nil {
+				s.HLClient.Logger.Error("err", "err", err)
+			}
+			return res, err
+		}
+
+		fTree.SetCurrentNode(targetNode)
 	}
 
 	centerFlex := tview.NewFlex().
@@ -176,88 +233,111 @@ func (mhc *Client) HandleGetFileNameList(ctx context.Context, c *hotline.Client,
 			AddItem(nil, 0, 1, false), 60, 1, true).
 		AddItem(nil, 0, 1, false)
 
-	mhc.Pages.AddPage("files", centerFlex, true, true)
-	mhc.App.Draw()
+	s.Pages.AddPage("files", centerFlex, true, true)
+	s.App.Draw()
 
 	return res, err
 }
 
-func (mhc *Client) TranGetMsgs(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	newsText := string(t.GetField(hotline.FieldData).Data)
-	newsText = strings.ReplaceAll(newsText, "\r", "\n")
+func (s *Session) TranGetMsgs(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	newsText := crToLF(s.decodeText(t.GetField(hotline.FieldData).Data))
 
 	newsTextView := tview.NewTextView().
 		SetText(newsText).
 		SetDoneFunc(func(key tcell.Key) {
-			mhc.Pages.SwitchToPage(serverUIPage)
-			mhc.App.SetFocus(mhc.chatInput)
+			s.Pages.SwitchToPage(s.pageName())
+			s.App.SetFocus(s.chatInput)
 		})
 	newsTextView.SetBorder(true).SetTitle("News")
 
-	mhc.Pages.AddPage("news", newsTextView, true, true)
-	// mhc.Pages.SwitchToPage("news")
-	// mhc.App.SetFocus(newsTextView)
-	mhc.App.Draw()
+	s.Pages.AddPage("news", newsTextView, true, true)
+	// s.Pages.SwitchToPage("news")
+	// s.App.SetFocus(newsTextView)
+	s.App.Draw()
 
 	return res, err
 }
 
-func (mhc *Client) HandleNotifyChangeUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+func (s *Session) HandleNotifyChangeUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	id, ok := userID(t)
+	if !ok {
+		return res, errors.New("invalid user ID")
+	}
 	newUser := hotline.User{
-		ID:    [2]byte(t.GetField(hotline.FieldUserID).Data),
-		Name:  string(t.GetField(hotline.FieldUserName).Data),
+		ID:    id,
+		Name:  s.decodeText(t.GetField(hotline.FieldUserName).Data),
 		Icon:  t.GetField(hotline.FieldUserIconID).Data,
 		Flags: t.GetField(hotline.FieldUserFlags).Data,
 	}
 
-	// Possible cases:
-	// user is new to the server
-	// user is already on the server but has a new name
-
-	var oldName string
-	var newUserList []hotline.User
-	updatedUser := false
-	for _, u := range mhc.UserList {
-		if newUser.ID == u.ID {
-			oldName = u.Name
-			u.Name = newUser.Name
-			if u.Name != newUser.Name {
-				_, _ = fmt.Fprintf(mhc.chatBox, " <<< "+oldName+" is now known as "+newUser.Name+" >>>\n")
+	// The user list, and everything derived from it, belongs to the UI goroutine.
+	s.App.QueueUpdateDraw(func() {
+		// Possible cases:
+		// user is new to the server
+		// user is already on the server but has a new name
+
+		// Check users under their previous names so that an ignored user stays ignored after a rename.
+		s.trackIgnoredUsers()
+
+		var newUserList []hotline.User
+		updatedUser := false
+		for _, u := range s.UserList {
+			if newUser.ID == u.ID {
+				s.announceUserChange(u, newUser)
+				u = newUser
+				updatedUser = true
 			}
-			updatedUser = true
+			newUserList = append(newUserList, u)
 		}
-		newUserList = append(newUserList, u)
-	}
 
-	if !updatedUser {
-		newUserList = append(newUserList, newUser)
-	}
+		if !updatedUser {
+			newUserList = append(newUserList, newUser)
+			s.userJoined[newUser.ID] = time.Now()
 
-	mhc.UserList = newUserList
+			if s.chatNotices().Joins && !s.isIgnored(newUser) {
+				s.chatNotice("%s has joined", newUser.Name)
+			}
+		}
+
+		s.UserList = newUserList
+		s.trackIgnoredUsers()
 
-	mhc.renderUserList()
+		s.renderUserList()
+		s.renderDashboardTable()
+	})
 
 	return res, err
 }
 
-func (mhc *Client) HandleNotifyDeleteUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+func (s *Session) HandleNotifyDeleteUser(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
 	exitUser := t.GetField(hotline.FieldUserID).Data
 
-	var newUserList []hotline.User
-	for _, u := range mhc.UserList {
-		if !bytes.Equal(exitUser, u.ID[:]) {
+	s.App.QueueUpdateDraw(func() {
+		var newUserList []hotline.User
+		for _, u := range s.UserList {
+			if bytes.Equal(exitUser, u.ID[:]) {
+				if s.chatNotices().Leaves && !s.isIgnored(u) {
+					s.chatNotice("%s has left", u.Name)
+				}
+				delete(s.userJoined, u.ID)
+				if s.dashboard != nil {
+					delete(s.dashboard.info, u.ID)
+				}
+				continue
+			}
 			newUserList = append(newUserList, u)
 		}
-	}
 
-	mhc.UserList = newUserList
+		s.UserList = newUserList
 
-	mhc.renderUserList()
+		s.renderUserList()
+		s.renderDashboardTable()
+	})
 
 	return res, err
 }
 
-func (mhc *Client) HandleClientGetUserNameList(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+func (s *Session) HandleClientGetUserNameList(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
 	var users []hotline.User
 	for _, field := range t.Fields {
 		// The Hotline protocol docs say that ClientGetUserNameList should only return hotline.FieldUserNameWithInfo (300)
@@ -268,98 +348,133 @@ func (mhc *Client) HandleClientGetUserNameList(ctx context.Context, c *hotline.C
 			if _, err := user.Write(field.Data); err != nil {
 				return res, fmt.Errorf("unable to read user data: %w", err)
 			}
+			user.Name = s.decodeText([]byte(user.Name))
 
 			users = append(users, user)
 		}
 	}
-	mhc.UserList = users
-	mhc.renderUserList()
+
+	s.App.QueueUpdateDraw(func() {
+		s.UserList = users
+		s.trackIgnoredUsers()
+		s.renderUserList()
+		s.renderDashboardTable()
+	})
 
 	return res, err
 }
 
-func (mhc *Client) renderUserList() {
-	mhc.userList.Clear()
-	for _, u := range mhc.UserList {
-		flagBitmap := big.NewInt(int64(binary.BigEndian.Uint16(u.Flags)))
-		if flagBitmap.Bit(hotline.UserFlagAdmin) == 1 {
-			_, _ = fmt.Fprintf(mhc.userList, "[red::b]%s[-:-:-]\n", u.Name)
-		} else {
-			_, _ = fmt.Fprintf(mhc.userList, "%s\n", u.Name)
+func (s *Session) HandleClientChatMsg(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	msg := s.decodeText(t.GetField(hotline.FieldData).Data)
+
+	// Messages in a private chat include the chat ID.
+	id, private := chatID(t)
+	private = private && id != [4]byte{}
+
+	s.App.QueueUpdateDraw(func() {
+		if line, ok := parseChatLine(msg); ok && s.isIgnoredChatName(line.Name) {
+			return
 		}
-		// TODO: fade if user is away
-	}
-}
 
-func (mhc *Client) HandleClientChatMsg(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	if c.Pref.EnableBell {
-		fmt.Println("\a")
-	}
+		chatBox := s.chatBox
+		if private {
+			pc, ok := s.privateChats[id]
+			if !ok {
+				return
+			}
+			if s.current == s {
+				s.Pages.ShowPage(pc.pageName())
+			}
+			chatBox = pc.chatBox
+		}
+
+		if c.Pref.EnableBell {
+			fmt.Println("\a")
+		}
 
-	_, _ = fmt.Fprintf(mhc.chatBox, "%s \n", t.GetField(hotline.FieldData).Data)
+		_, _ = fmt.Fprintln(chatBox, formatChatLine(time.Now(), msg))
+		s.markUnread()
+	})
 
 	return res, err
 }
 
-func (mhc *Client) HandleClientTranUserAccess(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	mhc.UserAccess = t.GetField(hotline.FieldUserAccess).Data
-
-	return res, err
+// sendAgreed tells the server we accept its agreement.
+func (s *Session) sendAgreed() error {
+	options := s.userOptions()
+	return s.HLClient.Send(hotline.NewTransaction(
+		hotline.TranAgreed, [2]byte{},
+		hotline.NewField(hotline.FieldUserName, s.encodeText(s.username())),
+		hotline.NewField(hotline.FieldUserIconID, s.iconBytes()),
+		hotline.NewField(hotline.FieldUserFlags, []byte{0x00, 0x00}),
+		hotline.NewField(hotline.FieldOptions, options[:]),
+	))
 }
 
-func (mhc *Client) HandleClientTranShowAgreement(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
-	agreement := string(t.GetField(hotline.FieldData).Data)
-	agreement = strings.ReplaceAll(agreement, "\r", "\n")
+func (s *Session) HandleClientTranShowAgreement(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	// The agreement was accepted on an earlier connection, so accept it again without asking.
+	if s.agreed {
+		return res, s.sendAgreed()
+	}
+
+	agreement := crToLF(s.decodeText(t.GetField(hotline.FieldData).Data))
 
 	agreeModal := tview.NewModal().
 		SetText(agreement).
 		AddButtons([]string{"Agree", "Disagree"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			if buttonIndex == 0 {
-				res = append(res,
-					hotline.NewTransaction(
-						hotline.TranAgreed, [2]byte{},
-						hotline.NewField(hotline.FieldUserName, []byte(c.Pref.Username)),
-						hotline.NewField(hotline.FieldUserIconID, c.Pref.IconBytes()),
-						hotline.NewField(hotline.FieldUserFlags, []byte{0x00, 0x00}),
-						hotline.NewField(hotline.FieldOptions, []byte{0x00, 0x00}),
-					),
-				)
-				mhc.Pages.HidePage("agreement")
-				mhc.App.SetFocus(mhc.chatInput)
+				s.agreed = true
+				if err := s.sendAgreed(); err != nil {
+					c.Logger.Error("Error accepting agreement", "err", err)
+				}
+				s.Pages.HidePage("agreement")
+				s.App.SetFocus(s.chatInput)
 			} else {
-				_ = c.Disconnect()
-				mhc.Pages.SwitchToPage("home")
+				s.Pages.RemovePage("agreement")
+				s.disconnect()
 			}
 		},
 		)
 
-	mhc.Pages.AddPage("agreement", agreeModal, false, true)
+	s.Pages.AddPage("agreement", agreeModal, false, true)
 
 	return res, err
 }
 
-func (mhc *Client) HandleClientTranLogin(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+func (s *Session) HandleClientTranLogin(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
 	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
-		errMsg := string(t.GetField(hotline.FieldError).Data)
+		errMsg := s.decodeText(t.GetField(hotline.FieldError).Data)
 		errModal := tview.NewModal()
 		errModal.SetText(errMsg)
 		errModal.AddButtons([]string{"Oh no"})
 		errModal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			mhc.Pages.RemovePage("errModal")
+			s.Pages.RemovePage("errModal")
 		})
-		mhc.Pages.RemovePage("joinServer")
-		mhc.Pages.AddPage("errModal", errModal, false, true)
+		s.Pages.RemovePage("joinServer")
+		s.Pages.AddPage("errModal", errModal, false, true)
 
-		mhc.App.Draw() // TODO: errModal doesn't render without this.  wtf?
+		s.App.Draw() // TODO: errModal doesn't render without this.  wtf?
 
-		c.Logger.Error(string(t.GetField(hotline.FieldError).Data))
-		return nil, errors.New("login error: " + string(t.GetField(hotline.FieldError).Data))
+		c.Logger.Error(errMsg)
+		s.loginRefused = true
+		return nil, errors.New("login error: " + errMsg)
 	}
-	mhc.Pages.AddAndSwitchToPage(serverUIPage, mhc.renderServerUI(), true)
-	mhc.App.SetFocus(mhc.chatInput)
+	s.App.QueueUpdateDraw(func() {
+		s.loggedIn = true
+		if s.Pages.HasPage(s.pageName()) {
+			s.rejoined()
+		} else {
+			s.Pages.AddPage(s.pageName(), s.renderServerUI(), true, false)
+			s.switchToSession(s)
+		}
+		if s.openURL != nil {
+			s.openPath(s.openURL)
+			s.openURL = nil
+		}
+	})
 
-	if err := c.Send(hotline.NewTransaction(hotline.TranGetUserNameList, [2]byte{})); err != nil {
+	if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetUserNameList, [2]byte{})); err != nil {
 		c.Logger.Error("err", "err", err)
 	}
 	return res, err
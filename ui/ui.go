@@ -3,6 +3,7 @@ package ui
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/jhalter/mobius/hotline"
@@ -11,6 +12,7 @@ import (
 	"log/slog"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -31,18 +33,59 @@ func (db *DebugBuffer) Write(p []byte) (int, error) {
 //}
 
 type Bookmark struct {
-	Name     string `yaml:"Name"`
-	Addr     string `yaml:"Addr"`
-	Login    string `yaml:"Login"`
-	Password string `yaml:"Password"`
+	Name        string `yaml:"Name"`
+	Addr        string `yaml:"Addr"`
+	Login       string `yaml:"Login"`
+	Password    string `yaml:"Password,omitempty"`    // Plaintext password, left by configs from before the vault
+	PasswordRef string `yaml:"PasswordRef,omitempty"` // ID of the password's entry in the credential vault
+	Encoding    string `yaml:"Encoding,omitempty"`    // Text encoding used by the server; defaults to macroman
+
+	Group       string `yaml:"Group,omitempty"`       // Folder the bookmark is shown in
+	Username    string `yaml:"Username,omitempty"`    // Name used on the server, overriding the global one
+	IconID      int    `yaml:"IconID,omitempty"`      // Icon used on the server, overriding the global one
+	AutoConnect bool   `yaml:"AutoConnect,omitempty"` // Connect to the server when the client starts
+
+	// Overrides of the global private message and chat preferences for this server
+	RefusePrivateMessages *bool `yaml:"RefusePrivateMessages,omitempty"`
+	RefusePrivateChat     *bool `yaml:"RefusePrivateChat,omitempty"`
+
+	HideChatNotices bool `yaml:"HideChatNotices,omitempty"` // Turns off join, leave and other user notices in chat
+	AutoReconnect   bool `yaml:"AutoReconnect,omitempty"`   // Reconnects with backoff when the connection is lost
+
+	TLS            bool   `yaml:"TLS,omitempty"`            // Connect over TLS, by default to port 5600
+	TLSFingerprint string `yaml:"TLSFingerprint,omitempty"` // SHA-256 fingerprint the server certificate is pinned to
+	TLSCAFile      string `yaml:"TLSCAFile,omitempty"`      // CA bundle used to verify the server, overriding the global one
+
+	passwordGuessed bool // An imported bookmark whose password might not have been read correctly
+
+	Proxy string `yaml:"Proxy,omitempty"` // Proxy URL overriding the global proxy; "direct" connects without one
 }
 
 type ClientPrefs struct {
-	Username   string     `yaml:"Username"`
-	IconID     int        `yaml:"IconID"`
-	Bookmarks  []Bookmark `yaml:"Bookmarks"`
-	Tracker    string     `yaml:"Tracker"`
-	EnableBell bool       `yaml:"EnableBell"`
+	Username   string       `yaml:"Username"`
+	IconID     int          `yaml:"IconID"`
+	Bookmarks  []Bookmark   `yaml:"Bookmarks"`
+	Tracker    string       `yaml:"Tracker,omitempty"` // Single tracker of older configs, replaced by Trackers
+	Trackers   []string     `yaml:"Trackers"`          // Tracker addresses; a tls:// prefix connects over TLS
+	EnableBell bool         `yaml:"EnableBell"`
+	Ignore     []IgnoreRule `yaml:"Ignore"`
+
+	AwayAfterMinutes int    `yaml:"AwayAfterMinutes"` // Minutes of inactivity before marking ourselves away; 0 disables
+	AutoResponse     string `yaml:"AutoResponse"`     // Message automatically sent to users who message us while away
+	KeepaliveSeconds int    `yaml:"KeepaliveSeconds"` // Seconds between keepalives sent to servers; 0 turns them off
+
+	RefusePrivateMessages bool `yaml:"RefusePrivateMessages"`
+	RefusePrivateChat     bool `yaml:"RefusePrivateChat"`
+
+	UserSort   string         `yaml:"UserSort"`   // User list sort order: join, name, admin or away
+	IconGlyphs map[int]string `yaml:"IconGlyphs"` // Glyphs shown for user icon IDs, overriding the built-in table
+
+	ChatNotices *ChatNotices `yaml:"ChatNotices"` // User changes announced in chat; joins, leaves and renames if unset
+
+	TLSCAFile  string            `yaml:"TLSCAFile"`  // CA bundle trusted in addition to the system roots
+	KnownCerts map[string]string `yaml:"KnownCerts"` // Fingerprints of certificates trusted on first use, keyed by address
+
+	Proxy string `yaml:"Proxy"` // socks5://, socks5h:// or http:// URL of a proxy for server and tracker connections
 }
 
 func (cp *ClientPrefs) IconBytes() []byte {
@@ -51,41 +94,51 @@ func (cp *ClientPrefs) IconBytes() []byte {
 	return iconBytes
 }
 
-func (cp *ClientPrefs) AddBookmark(_, addr, login, pass string) {
-	cp.Bookmarks = append(cp.Bookmarks, Bookmark{Addr: addr, Login: login, Password: pass})
+// bookmarkFor returns the bookmark for the server address, or nil if the server isn't bookmarked.
+func (cp *ClientPrefs) bookmarkFor(addr string) *Bookmark {
+	for i := range cp.Bookmarks {
+		b := &cp.Bookmarks[i]
+		if withDefaultPort(b.Addr, b.TLS) == withDefaultPort(addr, b.TLS) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (cp *ClientPrefs) AddBookmark(name, addr, login, pass string) {
+	cp.Bookmarks = append(cp.Bookmarks, Bookmark{Name: name, Addr: addr, Login: login, Password: pass})
 }
 
 type Client struct {
 	CfgPath    string
 	DebugBuf   *DebugBuffer
 	Connection net.Conn
-	UserAccess []byte
-	filePath   []string
-	UserList   []hotline.User
 	Logger     *slog.Logger
-	ServerName string
 
 	Pref *ClientPrefs
 
+	sessions      []*Session // Connected servers in the order of their tabs
+	current       *Session   // Session of the server UI last shown
+	nextSessionID int
+
+	lastActivity time.Time
+
 	Handlers map[uint16]hotline.ClientHandler
 
-	chatBox     *tview.TextView
-	chatInput   *tview.InputField
-	App         *tview.Application
-	Pages       *tview.Pages
-	userList    *tview.TextView
-	trackerList *tview.List
-	DebugBuffer *DebugBuffer
-	HLClient    *hotline.Client
+	App            *tview.Application
+	Pages          *tview.Pages
+	trackerBrowser *trackerBrowser // State of the tracker listing; nil when it isn't shown
+	vault          *vault          // Unlocked credential vault; nil while locked
+	startupTargets []startupTarget // Server given on the command line; the AutoConnect bookmarks are joined if nil
+	DebugBuffer    *DebugBuffer
 
 	Inbox chan *hotline.Transaction
 }
 
 // pages
 const (
-	pageServerUI    = "serverUI"
 	trackerListPage = "trackerList"
-	serverUIPage    = "serverUI"
+	serverUIPage    = "serverUI" // Prefix of the server UI page names, which are suffixed with the session ID
 )
 
 func NewUIClient(cfgPath string, logger *slog.Logger, db *DebugBuffer) *Client {
@@ -102,56 +155,56 @@ func NewUIClient(cfgPath string, logger *slog.Logger, db *DebugBuffer) *Client {
 		DebugBuf: db,
 	}
 
-	app := tview.NewApplication()
-	chatBox := tview.NewTextView().
-		SetScrollable(true).
-		SetDynamicColors(true).
-		SetWordWrap(true).
-		SetChangedFunc(func() {
-			app.Draw() // TODO: docs say this is bad but it's the only way to show content during initial render??
-		})
-	chatBox.Box.SetBorder(true).SetTitle("| Chat |")
-
-	chatInput := tview.NewInputField()
-	chatInput.
-		SetLabel("> ").
-		SetFieldBackgroundColor(tcell.ColorDimGray).
-		SetDoneFunc(func(key tcell.Key) {
-			// skip send if user hit enter with no other text
-			if len(chatInput.GetText()) == 0 {
-				return
-			}
-
-			t := hotline.NewTransaction(hotline.TranChatSend, [2]byte{},
-				hotline.NewField(hotline.FieldData, []byte(chatInput.GetText())),
-			)
-			_ = c.HLClient.Send(t)
-			chatInput.SetText("") // clear the input field after chat send
-		})
-
-	chatInput.Box.SetBorder(true).SetTitle("Send")
-
-	userList := tview.
-		NewTextView().
-		SetDynamicColors(true).
-		SetChangedFunc(func() {
-			app.Draw() // TODO: docs say this is bad but it's the only way to show content during initial render??
-		})
-	userList.Box.SetBorder(true).SetTitle("Users")
+	app := tview.NewApplication().EnablePaste(true)
 
 	c.App = app
-	c.chatBox = chatBox
 	c.Pages = tview.NewPages()
-	c.chatInput = chatInput
-	c.userList = userList
-	c.trackerList = tview.NewList()
-	c.HLClient = hotline.NewClient(c.Pref.Username, c.Logger)
 	//c.Pref = c.Pref
 	c.DebugBuffer = c.DebugBuf
 
 	return c
 }
 
+// sendChat sends the contents of the chat input to public chat and clears it.
+func (s *Session) sendChat() {
+	t := hotline.NewTransaction(hotline.TranChatSend, [2]byte{},
+		hotline.NewField(hotline.FieldData, s.encodeText(s.chatInput.HotlineText())),
+	)
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error sending chat", "err", err)
+	}
+	s.chatInput.Clear()
+}
+
+// confirmLargePaste asks the user to confirm before sending a large paste to public chat.
+func (s *Session) confirmLargePaste() {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Send %d lines of pasted text to public chat?", s.chatInput.LineCount())).
+		AddButtons([]string{"Cancel", "Send"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonIndex == 1 {
+				s.sendChat()
+			}
+			s.Pages.RemovePage("pasteWarning")
+			s.App.SetFocus(s.chatInput)
+		})
+	modal.Box.SetTitle("Large Paste")
+
+	s.Pages.AddPage("pasteWarning", modal, false, true)
+}
+
+// centered returns a Flex that centers the primitive on the screen with the given size.
+func centered(p tview.Primitive, width, height int) *tview.Flex {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
+
 func readConfig(cfgPath string) (*ClientPrefs, error) {
 	fh, err := os.Open(cfgPath)
 	if err != nil {
@@ -166,57 +219,46 @@ func readConfig(cfgPath string) (*ClientPrefs, error) {
 	return &prefs, nil
 }
 
-func (mhc *Client) showBookmarks() *tview.List {
-	list := tview.NewList()
-	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEsc {
-			mhc.Pages.SwitchToPage("home")
-		}
-		return event
-	})
-	list.Box.SetBorder(true).SetTitle("| Bookmarks |")
-
-	shortcut := 97 // rune for "a"
-	for i, srv := range mhc.Pref.Bookmarks {
-		addr := srv.Addr
-		login := srv.Login
-		pass := srv.Password
-		list.AddItem(srv.Name, srv.Addr, rune(shortcut+i), func() {
-			mhc.Pages.RemovePage("joinServer")
-
-			newJS := mhc.renderJoinServerForm("", addr, login, pass, "bookmarks", true, true)
+// savePrefs writes the client preferences to the config file.  The config can hold server logins, so it's only
+// readable by the user.
+func (mhc *Client) savePrefs() error {
+	out, err := yaml.Marshal(mhc.Pref)
+	if err != nil {
+		return fmt.Errorf("marshal prefs: %w", err)
+	}
 
-			mhc.Pages.AddPage("joinServer", newJS, true, true)
-		})
+	if err := writeFileAtomic(mhc.CfgPath, out, 0600); err != nil {
+		return fmt.Errorf("write config file: %w", err)
 	}
 
-	return list
+	return nil
 }
 
-func (mhc *Client) getTrackerList(servers []hotline.ServerRecord) *tview.List {
-	list := tview.NewList()
-	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEsc {
-			mhc.Pages.SwitchToPage("home")
-		}
-		return event
-	})
-	list.Box.SetBorder(true).SetTitle("| Servers |")
-
-	const shortcut = 97 // rune for "a"
-	for i := range servers {
-		srv := servers[i]
-		list.AddItem(string(srv.Name), string(srv.Description), rune(shortcut+i), func() {
-			mhc.Pages.RemovePage("joinServer")
-
-			newJS := mhc.renderJoinServerForm(string(srv.Name), srv.Addr(), hotline.GuestAccount, "", trackerListPage, false, true)
+// writeFileAtomic replaces the file atomically, by writing a temporary file next to it and renaming it over the file,
+// so a crash or full disk can't leave it truncated.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
 
-			mhc.Pages.AddPage("joinServer", newJS, true, true)
-			mhc.Pages.ShowPage("joinServer")
-		})
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
 	}
 
-	return list
+	return os.Rename(tmp.Name(), path)
 }
 
 func (mhc *Client) renderSettingsForm() *tview.Flex {
@@ -227,8 +269,21 @@ func (mhc *Client) renderSettingsForm() *tview.Flex {
 		_, err := strconv.Atoi(idStr)
 		return err == nil
 	}, nil)
-	settingsForm.AddInputField("Tracker", mhc.Pref.Tracker, 0, nil, nil)
+	settingsForm.AddInputField("Trackers", strings.Join(mhc.Pref.trackers(), ", "), 0, nil, nil)
 	settingsForm.AddCheckbox("Enable Terminal Bell", mhc.Pref.EnableBell, nil)
+	settingsForm.AddInputField("Away After (min)", strconv.Itoa(mhc.Pref.AwayAfterMinutes), 0, func(minStr string, _ rune) bool {
+		_, err := strconv.Atoi(minStr)
+		return err == nil
+	}, nil)
+	settingsForm.AddInputField("Away Message", mhc.Pref.AutoResponse, 0, nil, nil)
+	settingsForm.AddCheckbox("Refuse Private Messages", mhc.Pref.RefusePrivateMessages, nil)
+	settingsForm.AddCheckbox("Refuse Private Chat", mhc.Pref.RefusePrivateChat, nil)
+	settingsForm.AddInputField("Keepalive (sec, 0 off)", strconv.Itoa(mhc.Pref.KeepaliveSeconds), 0, func(secStr string, _ rune) bool {
+		_, err := strconv.Atoi(secStr)
+		return err == nil
+	}, nil)
+	settingsForm.AddInputField("CA Bundle", mhc.Pref.TLSCAFile, 0, nil, nil)
+	settingsForm.AddInputField("Proxy URL", mhc.Pref.Proxy, 0, nil, nil)
 	settingsForm.AddButton("Save", func() {
 		usernameInput := settingsForm.GetFormItem(0).(*tview.InputField).GetText()
 		if len(usernameInput) == 0 {
@@ -237,19 +292,33 @@ func (mhc *Client) renderSettingsForm() *tview.Flex {
 		mhc.Pref.Username = usernameInput
 		iconStr = settingsForm.GetFormItem(1).(*tview.InputField).GetText()
 		mhc.Pref.IconID, _ = strconv.Atoi(iconStr)
-		mhc.Pref.Tracker = settingsForm.GetFormItem(2).(*tview.InputField).GetText()
+		mhc.Pref.Trackers = nil
+		for _, tracker := range strings.Split(settingsForm.GetFormItem(2).(*tview.InputField).GetText(), ",") {
+			if tracker = strings.TrimSpace(tracker); tracker != "" {
+				mhc.Pref.Trackers = append(mhc.Pref.Trackers, tracker)
+			}
+		}
+		mhc.Pref.Tracker = ""
 		mhc.Pref.EnableBell = settingsForm.GetFormItem(3).(*tview.Checkbox).IsChecked()
-
-		out, err := yaml.Marshal(&mhc.Pref)
-		if err != nil {
-			// TODO: handle err
+		mhc.Pref.AwayAfterMinutes, _ = strconv.Atoi(settingsForm.GetFormItem(4).(*tview.InputField).GetText())
+		mhc.Pref.AutoResponse = settingsForm.GetFormItem(5).(*tview.InputField).GetText()
+		mhc.Pref.RefusePrivateMessages = settingsForm.GetFormItem(6).(*tview.Checkbox).IsChecked()
+		mhc.Pref.RefusePrivateChat = settingsForm.GetFormItem(7).(*tview.Checkbox).IsChecked()
+		mhc.Pref.KeepaliveSeconds, _ = strconv.Atoi(settingsForm.GetFormItem(8).(*tview.InputField).GetText())
+		mhc.Pref.TLSCAFile = settingsForm.GetFormItem(9).(*tview.InputField).GetText()
+		mhc.Pref.Proxy = settingsForm.GetFormItem(10).(*tview.InputField).GetText()
+
+		if err := mhc.savePrefs(); err != nil {
+			mhc.Logger.Error("Error saving settings", "err", err)
 		}
-		// TODO: handle err
-		err = os.WriteFile(mhc.CfgPath, out, 0666)
-		if err != nil {
-			println(mhc.CfgPath)
-			panic(err)
+
+		// Apply changed name, icon and options to the connected servers.
+		for _, s := range mhc.sessions {
+			if err := s.sendUserInfo(); err != nil {
+				s.Logger.Error("Error sending user info", "err", err)
+			}
 		}
+
 		mhc.Pages.RemovePage("settings")
 	})
 	settingsForm.SetBorder(true)
@@ -265,99 +334,161 @@ func (mhc *Client) renderSettingsForm() *tview.Flex {
 		AddItem(tview.NewFlex().
 			SetDirection(tview.FlexRow).
 			AddItem(nil, 0, 1, false).
-			AddItem(settingsForm, 15, 1, true).
+			AddItem(settingsForm, 29, 1, true).
 			AddItem(nil, 0, 1, false), 40, 1, true).
 		AddItem(nil, 0, 1, false)
 
 	return centerFlex
 }
 
-func (mhc *Client) joinServer(addr, login, password string) error {
-	// append default port to address if no port supplied
+// withDefaultPort appends the default Hotline port, or the default TLS port if useTLS is set, to the address if no
+// port is supplied.
+func withDefaultPort(addr string, useTLS bool) string {
 	if len(strings.Split(addr, ":")) == 1 {
-		addr += ":5500"
+		if useTLS {
+			return net.JoinHostPort(addr, defaultTLSPort)
+		}
+		return net.JoinHostPort(addr, defaultPort)
 	}
-	if err := mhc.HLClient.Connect(addr, login, password); err != nil {
-		return fmt.Errorf("Error joining server: %v\n", err)
+	return addr
+}
+
+// joinServer connects to a server in a new session, which is shown once login completes.  If open is set, its folder
+// or file is shown in the Files view after login.
+func (mhc *Client) joinServer(name, addr, login, password string, useTLS bool, open *hotlineURL) error {
+	s := mhc.newSession(name, withDefaultPort(addr, useTLS), login, password, useTLS)
+	s.openURL = open
+
+	if err := s.connect(s.HLClient); err != nil {
+		return fmt.Errorf("Error joining server: %w", err)
 	}
+	mhc.sessions = append(mhc.sessions, s)
+	mhc.lastActivity = time.Now()
 
 	go func() {
-		if err := mhc.HLClient.HandleTransactions(context.TODO()); err != nil {
-			mhc.Pages.SwitchToPage("home")
-		}
+		for {
+			done := make(chan struct{})
+			if s.startKeepalives() {
+				go s.heartbeat(s.HLClient, s.keepalives, done)
+			}
 
-		loginErrModal := tview.NewModal().
-			AddButtons([]string{"Ok"}).
-			SetText("The server connection has closed.").
-			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-				mhc.Pages.SwitchToPage("home")
+			_ = s.HLClient.HandleTransactions(context.TODO())
+			close(done)
+
+			var retry bool
+			mhc.App.QueueUpdate(func() {
+				retry = s.shouldReconnect()
 			})
-		loginErrModal.Box.SetTitle("Server Connection Error")
+			if !retry || !s.reconnect() {
+				break
+			}
+		}
+
+		mhc.App.QueueUpdateDraw(func() {
+			mhc.removeSession(s)
+			if s.disconnecting {
+				return
+			}
+
+			closedMsg := fmt.Sprintf("The connection to %s has closed.", s.ServerName)
+			if s.disconnectReason != "" {
+				closedMsg = fmt.Sprintf("You were disconnected by %s:\n\n%s", s.ServerName, s.disconnectReason)
+			}
+
+			loginErrModal := tview.NewModal().
+				AddButtons([]string{"Ok"}).
+				SetText(closedMsg).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					mhc.Pages.RemovePage("loginErr")
+				})
+			loginErrModal.Box.SetTitle("Server Connection Error")
 
-		mhc.Pages.AddPage("loginErr", loginErrModal, false, true)
-		mhc.App.Draw()
+			mhc.Pages.AddPage("loginErr", loginErrModal, false, true)
+		})
 	}()
 
 	return nil
 }
 
 func (mhc *Client) renderJoinServerForm(name, server, login, password, backPage string, save, defaultConnect bool) *tview.Flex {
+	var useTLS bool
+	if b := mhc.Pref.bookmarkFor(server); b != nil {
+		useTLS = b.TLS
+	}
+
 	joinServerForm := tview.NewForm()
 	joinServerForm.
 		AddInputField("Server", server, 0, nil, nil).
 		AddInputField("Login", login, 0, nil, nil).
 		AddPasswordField("Password", password, 0, '*', nil).
-		AddCheckbox("Save", save, func(checked bool) {
-			mhc.Pref.AddBookmark(
-				joinServerForm.GetFormItem(0).(*tview.InputField).GetText(),
-				joinServerForm.GetFormItem(0).(*tview.InputField).GetText(),
-				joinServerForm.GetFormItem(1).(*tview.InputField).GetText(),
-				joinServerForm.GetFormItem(2).(*tview.InputField).GetText(),
-			)
-
-			out, err := yaml.Marshal(mhc.Pref)
-			if err != nil {
-				panic(err)
+		AddCheckbox("Save", save, nil).
+		AddCheckbox("TLS", useTLS, nil)
+
+	var connect func()
+	connect = func() {
+		srvAddr := joinServerForm.GetFormItem(0).(*tview.InputField).GetText()
+		loginInput := joinServerForm.GetFormItem(1).(*tview.InputField).GetText()
+		passwordInput := joinServerForm.GetFormItem(2).(*tview.InputField).GetText()
+		tlsInput := joinServerForm.GetFormItem(4).(*tview.Checkbox).IsChecked()
+
+		// Save checkbox.  The bookmark is saved before connecting so the new session uses it.  Its password goes into
+		// the vault, so connecting waits for the vault to be unlocked.
+		if joinServerForm.GetFormItem(3).(*tview.Checkbox).IsChecked() && mhc.Pref.bookmarkFor(srvAddr) == nil {
+			bookmarkName := name
+			if bookmarkName == "" {
+				bookmarkName = srvAddr
 			}
-
-			err = os.WriteFile(mhc.CfgPath, out, 0666)
-			if err != nil {
-				panic(err)
+			save := func() {
+				mhc.Pref.AddBookmark(bookmarkName, srvAddr, loginInput, "")
+				b := &mhc.Pref.Bookmarks[len(mhc.Pref.Bookmarks)-1]
+				b.TLS = tlsInput
+				if err := mhc.setBookmarkPassword(b, passwordInput); err != nil {
+					mhc.Logger.Error("Error saving password to vault", "err", err)
+				}
+				mhc.bookmarksChanged()
 			}
-		}).
+			if passwordInput != "" && mhc.vault == nil {
+				mhc.unlockVault(func() {
+					save()
+					connect()
+				}, func() {
+					// The password can't be saved without the vault, so leave the bookmark for another time.
+					joinServerForm.GetFormItem(3).(*tview.Checkbox).SetChecked(false)
+					joinServerForm.SetTitle("| Connect | Not saved: the password vault is locked |")
+				})
+				return
+			}
+			save()
+		}
+
+		if name == "" {
+			name = fmt.Sprintf("%s@%s", loginInput, srvAddr)
+		}
+		err := mhc.joinServer(name, srvAddr, loginInput, passwordInput, tlsInput, nil)
+
+		var certErr *untrustedCertError
+		if errors.As(err, &certErr) {
+			mhc.confirmTrustCert(certErr, connect)
+			return
+		}
+
+		if err != nil {
+			mhc.Logger.Error("login error", "err", err)
+			loginErrModal := tview.NewModal().
+				AddButtons([]string{"Oh no"}).
+				SetText(err.Error()).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					mhc.Pages.SwitchToPage(backPage)
+				})
+
+			mhc.Pages.AddPage("loginErr", loginErrModal, false, true)
+		}
+	}
+	joinServerForm.
 		AddButton("Cancel", func() {
 			mhc.Pages.SwitchToPage(backPage)
 		}).
-		AddButton("Connect", func() {
-			srvAddr := joinServerForm.GetFormItem(0).(*tview.InputField).GetText()
-			loginInput := joinServerForm.GetFormItem(1).(*tview.InputField).GetText()
-			err := mhc.joinServer(
-				srvAddr,
-				loginInput,
-				joinServerForm.GetFormItem(2).(*tview.InputField).GetText(),
-			)
-			if name == "" {
-				name = fmt.Sprintf("%s@%s", loginInput, srvAddr)
-			}
-			mhc.ServerName = name
-
-			if err != nil {
-				mhc.HLClient.Logger.Error("login error", "err", err)
-				loginErrModal := tview.NewModal().
-					AddButtons([]string{"Oh no"}).
-					SetText(err.Error()).
-					SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-						mhc.Pages.SwitchToPage(backPage)
-					})
-
-				mhc.Pages.AddPage("loginErr", loginErrModal, false, true)
-			}
-
-			// Save checkbox
-			if joinServerForm.GetFormItem(3).(*tview.Checkbox).IsChecked() {
-				// TODO: implement bookmark saving
-			}
-		})
+		AddButton("Connect", connect)
 
 	joinServerForm.Box.SetBorder(true).SetTitle("| Connect |")
 	joinServerForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -368,7 +499,7 @@ func (mhc *Client) renderJoinServerForm(name, server, login, password, backPage
 	})
 
 	if defaultConnect {
-		joinServerForm.SetFocus(5)
+		joinServerForm.SetFocus(6)
 	}
 
 	joinServerPage := tview.NewFlex().
@@ -376,71 +507,132 @@ func (mhc *Client) renderJoinServerForm(name, server, login, password, backPage
 		AddItem(tview.NewFlex().
 			SetDirection(tview.FlexRow).
 			AddItem(nil, 0, 1, false).
-			AddItem(joinServerForm, 14, 1, true).
+			AddItem(joinServerForm, 16, 1, true).
 			AddItem(nil, 0, 1, false), 40, 1, true).
 		AddItem(nil, 0, 1, false)
 
 	return joinServerPage
 }
 
-func (mhc *Client) renderServerUI() *tview.Flex {
-	mhc.chatBox.SetText("") // clear any previously existing chatbox text
+func (s *Session) renderServerUI() *tview.Flex {
+	s.chatBox.SetText("") // clear any previously existing chatbox text
 	commandList := tview.NewTextView().SetDynamicColors(true)
 	commandList.
-		SetText("[yellow]^n[-::]: Read News   [yellow]^p[-::]: Post News\n[yellow]^l[-::]: View Logs   [yellow]^f[-::]: View Files\n").
 		SetBorder(true).
 		SetTitle("| Keyboard Shortcuts| ")
+	s.commandList = commandList
+	s.applyPrivileges()
 
 	modal := tview.NewModal().
 		SetText("Disconnect from the server?").
-		AddButtons([]string{"Cancel", "Exit"}).
-		SetFocus(1)
+		AddButtons([]string{"Cancel", "Home", "Exit"}).
+		SetFocus(2)
 	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-		if buttonIndex == 1 {
-			_ = mhc.HLClient.Disconnect()
-			mhc.Pages.RemovePage(pageServerUI)
-			mhc.Pages.SwitchToPage("home")
-		} else {
-			mhc.Pages.HidePage("modal")
+		switch buttonIndex {
+		case 1:
+			s.Pages.RemovePage("modal")
+			s.Pages.SwitchToPage("home")
+		case 2:
+			s.Pages.RemovePage("modal")
+			s.disconnect()
+		default:
+			s.Pages.HidePage("modal")
 		}
 	})
 
-	serverUI := tview.NewFlex().
+	chatFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(commandList, 5, 0, false).
+		AddItem(s.chatBox, 0, 8, false).
+		AddItem(s.chatInput, 3, 0, true)
+
+	// Grow the chat input with its contents, up to a limit.
+	const maxChatInputLines = 6
+	s.chatInput.SetChangedFunc(func() {
+		chatFlex.ResizeItem(s.chatInput, min(s.chatInput.LineCount(), maxChatInputLines)+2, 0)
+	})
+
+	serverFlex := tview.NewFlex().
+		AddItem(chatFlex, 0, 1, true).
+		AddItem(s.userPane, 25, 1, false)
+
+	serverUI := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(tview.NewFlex().
-			SetDirection(tview.FlexRow).
-			AddItem(commandList, 4, 0, false).
-			AddItem(mhc.chatBox, 0, 8, false).
-			AddItem(mhc.chatInput, 3, 0, true), 0, 1, true).
-		AddItem(mhc.userList, 25, 1, false)
-	serverUI.SetBorder(true).SetTitle("| Mobius - Connected to " + mhc.ServerName + " |").SetTitleAlign(tview.AlignLeft)
+			AddItem(s.tabBar, 0, 1, false).
+			AddItem(s.statusBar, 16, 0, false), 1, 0, false).
+		AddItem(serverFlex, 0, 1, true)
+	serverUI.SetBorder(true).SetTitle("| Mobius - Connected to " + s.ServerName + " |").SetTitleAlign(tview.AlignLeft)
 	serverUI.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			mhc.Pages.AddPage("modal", modal, false, true)
+		if event.Key() == tcell.KeyEscape && !s.userFilter.HasFocus() {
+			s.Pages.AddPage("modal", modal, false, true)
 		}
 
 		// List files
 		if event.Key() == tcell.KeyCtrlF {
-			if err := mhc.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{})); err != nil {
-				mhc.HLClient.Logger.Error("err", "err", err)
+			if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{})); err != nil {
+				s.Logger.Error("err", "err", err)
+			}
+		}
+
+		// Switch focus between the chat input and user list
+		if event.Key() == tcell.KeyTab {
+			if s.userPane.HasFocus() {
+				s.App.SetFocus(s.chatInput)
+			} else {
+				s.App.SetFocus(s.userList)
 			}
+			return nil
+		}
+
+		// Private message and chat options
+		if event.Key() == tcell.KeyCtrlO {
+			s.Pages.AddPage("serverOptions", s.renderServerOptions(), true, true)
+			return nil
+		}
+
+		// Toggle away status
+		if event.Key() == tcell.KeyCtrlT {
+			s.toggleAway()
+			return nil
+		}
+
+		// Admin dashboard
+		if event.Key() == tcell.KeyCtrlS && s.privileges.GetUserInfo {
+			s.openDashboard()
+			return nil
+		}
+
+		// Broadcast
+		if event.Key() == tcell.KeyCtrlR && s.privileges.Broadcast {
+			s.showBroadcast()
+			return nil
+		}
+
+		// Account administration
+		if event.Key() == tcell.KeyCtrlG && s.privileges.OpenAccounts {
+			s.openAccounts()
+			return nil
+		}
+
+		// Ignore list
+		if event.Key() == tcell.KeyCtrlK {
+			s.Pages.AddPage("ignoreList", s.renderIgnoreList(), true, true)
+			return nil
 		}
 
 		// Show News
-		if event.Key() == tcell.KeyCtrlN {
-			if err := mhc.HLClient.Send(hotline.NewTransaction(hotline.TranGetMsgs, [2]byte{})); err != nil {
-				mhc.HLClient.Logger.Error("err", "err", err)
+		if event.Key() == tcell.KeyCtrlN && s.privileges.ReadNews {
+			if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetMsgs, [2]byte{})); err != nil {
+				s.Logger.Error("err", "err", err)
 			}
 		}
 
 		// Post news
-		if event.Key() == tcell.KeyCtrlP {
+		if event.Key() == tcell.KeyCtrlP && s.privileges.PostNews {
 			newsFlex := tview.NewFlex()
 			newsFlex.SetBorderPadding(0, 0, 1, 1)
-			newsPostTextArea := tview.NewTextView()
-			newsPostTextArea.SetBackgroundColor(tcell.ColorDarkSlateGrey)
-			newsPostTextArea.SetChangedFunc(func() {
-				mhc.App.Draw() // TODO: docs say this is bad but it's the only way to show content during initial render??
-			})
+			newsPostTextArea := NewComposer()
+			newsPostTextArea.SetTextStyle(tcell.StyleDefault.Background(tcell.ColorDarkSlateGrey))
 
 			newsPostForm := tview.NewForm().
 				SetButtonsAlign(tview.AlignRight).
@@ -449,24 +641,24 @@ func (mhc *Client) renderServerUI() *tview.Flex {
 			newsPostForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 				switch event.Key() {
 				case tcell.KeyEscape:
-					mhc.Pages.RemovePage("newsInput")
+					s.Pages.RemovePage("newsInput")
 				case tcell.KeyTab:
-					mhc.App.SetFocus(newsPostTextArea)
+					s.App.SetFocus(newsPostTextArea)
 				case tcell.KeyEnter:
-					newsText := strings.ReplaceAll(newsPostTextArea.GetText(true), "\n", "\r")
+					newsText := newsPostTextArea.HotlineText()
 					if len(newsText) == 0 {
 						return event
 					}
-					err := mhc.HLClient.Send(
+					err := s.HLClient.Send(
 						hotline.NewTransaction(hotline.TranOldPostNews, [2]byte{},
-							hotline.NewField(hotline.FieldData, []byte(newsText)),
+							hotline.NewField(hotline.FieldData, s.encodeText(newsText)),
 						),
 					)
 					if err != nil {
-						mhc.HLClient.Logger.Error("Error posting news", "err", err)
+						s.Logger.Error("Error posting news", "err", err)
 						// TODO: display errModal to user
 					}
-					mhc.Pages.RemovePage("newsInput")
+					s.Pages.RemovePage("newsInput")
 				}
 
 				return event
@@ -480,24 +672,10 @@ func (mhc *Client) renderServerUI() *tview.Flex {
 			newsPostTextArea.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 				switch event.Key() {
 				case tcell.KeyEscape:
-					mhc.Pages.RemovePage("newsInput")
+					s.Pages.RemovePage("newsInput")
 				case tcell.KeyTab:
-					mhc.App.SetFocus(newsPostForm)
-				case tcell.KeyEnter:
-					_, _ = fmt.Fprintf(newsPostTextArea, "\n")
-				default:
-					const windowsBackspaceRune = 8
-					const macBackspaceRune = 127
-					switch event.Rune() {
-					case macBackspaceRune, windowsBackspaceRune:
-						curTxt := newsPostTextArea.GetText(true)
-						if len(curTxt) > 0 {
-							curTxt = curTxt[:len(curTxt)-1]
-							newsPostTextArea.SetText(curTxt)
-						}
-					default:
-						_, _ = fmt.Fprint(newsPostTextArea, string(event.Rune()))
-					}
+					s.App.SetFocus(newsPostForm)
+					return nil
 				}
 
 				return event
@@ -516,8 +694,8 @@ func (mhc *Client) renderServerUI() *tview.Flex {
 					AddItem(nil, 0, 1, false), 40, 1, false).
 				AddItem(nil, 0, 1, false)
 
-			mhc.Pages.AddPage("newsInput", newsPostPage, true, true)
-			mhc.App.SetFocus(newsPostTextArea)
+			s.Pages.AddPage("newsInput", newsPostPage, true, true)
+			s.App.SetFocus(newsPostTextArea)
 		}
 
 		return event
@@ -549,28 +727,15 @@ func (mhc *Client) Start() {
 		joinServerPage := mhc.renderJoinServerForm("", "", hotline.GuestAccount, "", "home", false, false)
 		mhc.Pages.AddPage("joinServer", joinServerPage, true, true)
 	}).
+		AddItem("Connected Servers", "", 'c', func() {
+			mhc.Pages.AddPage("sessions", centered(mhc.renderSessionList(), 60, 20), true, true)
+		}).
 		AddItem("Bookmarks", "", 'b', func() {
 			mhc.Pages.AddAndSwitchToPage("bookmarks", mhc.showBookmarks(), true)
 		}).
-		AddItem("Browse Tracker", "", 't', func() {
-			conn, _ := net.DialTimeout("tcp", mhc.Pref.Tracker, 5*time.Second)
-
-			listing, err := hotline.GetListing(conn)
-			if err != nil {
-				errMsg := fmt.Sprintf("Error fetching tracker results:\n%v", err)
-				errModal := tview.NewModal()
-				errModal.SetText(errMsg)
-				errModal.AddButtons([]string{"Cancel"})
-				errModal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					mhc.Pages.RemovePage("errModal")
-				})
-				mhc.Pages.RemovePage("joinServer")
-				mhc.Pages.AddPage("errModal", errModal, false, true)
-				return
-			}
-			mhc.trackerList = mhc.getTrackerList(listing)
-			mhc.Pages.AddAndSwitchToPage("trackerList", mhc.trackerList, true)
-		}).
+		AddItem("Open Link", "", 'o', mhc.showOpenLink).
+		AddItem("Import Bookmarks", "", 'i', mhc.showImportBookmarks).
+		AddItem("Browse Trackers", "", 't', mhc.browseTracker).
 		AddItem("Settings", "", 's', func() {
 			mhc.Pages.AddPage("settings", mhc.renderSettingsForm(), true, true)
 		}).
@@ -579,11 +744,22 @@ func (mhc *Client) Start() {
 		})
 
 	mhc.Pages.AddPage("home", home, true, true)
+	mhc.offerPasswordMigration()
+
+	// Servers to join at startup are connected once the app runs, and shown instead of the home menu once logged in.
+	// Queueing waits for the update to run, so it's done from another goroutine to not block until then.
+	if len(mhc.autoConnectTargets()) > 0 {
+		go mhc.App.QueueUpdateDraw(mhc.autoConnect)
+	}
+
+	go mhc.watchIdle()
 
 	// App level input capture
 	mhc.App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		mhc.userActivity()
+
 		if event.Key() == tcell.KeyCtrlC {
-			mhc.HLClient.Logger.Info("Exiting")
+			mhc.Logger.Info("Exiting")
 			mhc.App.Stop()
 			os.Exit(0)
 		}
@@ -599,6 +775,23 @@ func (mhc *Client) Start() {
 
 			mhc.Pages.AddPage("logs", mhc.DebugBuffer.TextView, true, true)
 		}
+
+		// Switch between connected servers
+		if event.Modifiers()&tcell.ModAlt != 0 {
+			switch {
+			case event.Key() == tcell.KeyLeft:
+				mhc.cycleSession(-1)
+				return nil
+			case event.Key() == tcell.KeyRight:
+				mhc.cycleSession(1)
+				return nil
+			case event.Key() == tcell.KeyRune && event.Rune() >= '1' && event.Rune() <= '9':
+				if i := int(event.Rune() - '1'); i < len(mhc.sessions) {
+					mhc.switchToSession(mhc.sessions[i])
+				}
+				return nil
+			}
+		}
 		return event
 	})
 
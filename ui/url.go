@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const hotlineScheme = "hotline"
+
+// hotlineURL is a parsed hotline://[login[:password]@]host[:port][/path] URL.  A path ending in a slash is a folder;
+// otherwise its last name may be a folder or a file, which is only known once the folder it's in is listed.
+type hotlineURL struct {
+	Addr     string   // Server address; without a port if the URL has none, so the bookmark's TLS setting picks it
+	Login    string   // Account login; guest unless the URL names one
+	Password string   // Account password, if the URL includes one
+	Path     []string // Path of a folder or file on the server, if any
+	Folder   bool     // The path names a folder
+}
+
+// isHotlineURL reports whether s looks like a hotline:// URL rather than a bookmark name.
+func isHotlineURL(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), hotlineScheme+"://")
+}
+
+// parseHotlineURL parses a hotline:// URL.
+func parseHotlineURL(rawURL string) (*hotlineURL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Scheme, hotlineScheme) {
+		return nil, fmt.Errorf("not a %s:// URL: %s", hotlineScheme, rawURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("URL has no server: %s", rawURL)
+	}
+
+	hu := &hotlineURL{
+		Addr:   u.Host,
+		Login:  hotline.GuestAccount,
+		Folder: strings.HasSuffix(u.Path, "/"),
+	}
+	if u.User != nil {
+		if login := u.User.Username(); login != "" {
+			hu.Login = login
+		}
+		hu.Password, _ = u.User.Password()
+	}
+
+	for _, name := range strings.Split(u.Path, "/") {
+		if name != "" {
+			hu.Path = append(hu.Path, name)
+		}
+	}
+
+	if len(hu.Path) == 0 {
+		hu.Folder = false
+	}
+
+	return hu, nil
+}
+
+// String returns the URL, leaving out the password and the login if it's guest.
+func (hu *hotlineURL) String() string {
+	u := url.URL{Scheme: hotlineScheme, Host: hu.Addr, Path: "/"}
+	if host, port, err := net.SplitHostPort(hu.Addr); err == nil && port == defaultPort {
+		u.Host = host
+	}
+	if hu.Login != "" && hu.Login != hotline.GuestAccount {
+		u.User = url.User(hu.Login)
+	}
+
+	u.Path += strings.Join(hu.Path, "/")
+	if hu.Folder {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+// sessionFor returns the session logged in to the server with the login, or nil if there is none.
+func (mhc *Client) sessionFor(addr, login string) *Session {
+	for _, s := range mhc.sessions {
+		if withDefaultPort(addr, s.useTLS) == s.serverAddr && s.login == login {
+			return s
+		}
+	}
+	return nil
+}
+
+// openHotlineURL shows the server, folder or file of the URL, reusing the session for the server and login if there
+// is one, and connecting otherwise.
+func (mhc *Client) openHotlineURL(u *hotlineURL) {
+	if s := mhc.sessionFor(u.Addr, u.Login); s != nil {
+		mhc.switchToSession(s)
+		s.openPath(u)
+		return
+	}
+
+	t := startupTarget{url: u}
+	if b := mhc.Pref.bookmarkFor(u.Addr); b != nil {
+		bookmark := *b
+		t.bookmark = &bookmark
+	}
+	if t.needsVault() && mhc.vault == nil {
+		mhc.unlockVault(func() { mhc.connectTarget(t) }, nil)
+		return
+	}
+	mhc.connectTarget(t)
+}
+
+// openPath lists the folder of the URL's path in the Files view, then selects the file or opens the folder the path
+// ends with once the listing arrives.
+func (s *Session) openPath(u *hotlineURL) {
+	if len(u.Path) == 0 {
+		return
+	}
+
+	s.fileTarget = ""
+	if u.Folder {
+		s.filePath = slices.Clone(u.Path)
+	} else {
+		s.filePath = slices.Clone(u.Path[:len(u.Path)-1])
+		s.fileTarget = u.Path[len(u.Path)-1]
+	}
+
+	var fields []hotline.Field
+	if len(s.filePath) > 0 {
+		fields = append(fields, s.filePathField(s.filePath))
+	}
+	if err := s.HLClient.Send(hotline.NewTransaction(hotline.TranGetFileNameList, [2]byte{}, fields...)); err != nil {
+		s.Logger.Error("Error listing files", "err", err)
+	}
+}
+
+// filePathField returns the file path field for a folder, with its names in the server's text encoding.
+func (s *Session) filePathField(path []string) hotline.Field {
+	names := make([]string, len(path))
+	for i, name := range path {
+		names[i] = string(s.encodeText(name))
+	}
+	return hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath(strings.Join(names, "/")))
+}
+
+// fileURL returns the URL of a file or folder on the session's server.
+func (s *Session) fileURL(path []string, folder bool) string {
+	addr := s.serverAddr
+	if host, port, err := net.SplitHostPort(addr); err == nil && s.useTLS && port == defaultTLSPort {
+		// Keep the port out of links to bookmarked TLS servers, as they're opened with the bookmark's TLS setting.
+		if b := s.bookmark; b != nil && b.TLS {
+			addr = host
+		}
+	}
+	u := hotlineURL{Addr: addr, Login: s.login, Path: path, Folder: folder && len(path) > 0}
+	return u.String()
+}
+
+// showOpenLink shows a form for opening a hotline:// link.
+func (mhc *Client) showOpenLink() {
+	form := tview.NewForm().AddInputField("Link", "", 0, nil, nil)
+	form.
+		AddButton("Open", func() {
+			u, err := parseHotlineURL(strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText()))
+			if err != nil {
+				form.SetTitle(fmt.Sprintf("| %s |", err))
+				return
+			}
+			mhc.Pages.RemovePage("openLink")
+			mhc.openHotlineURL(u)
+		}).
+		AddButton("Cancel", func() {
+			mhc.Pages.RemovePage("openLink")
+		})
+	form.SetBorder(true).SetTitle("| Open Link |")
+	form.SetCancelFunc(func() {
+		mhc.Pages.RemovePage("openLink")
+	})
+
+	mhc.Pages.AddPage("openLink", centered(form, 70, 7), true, true)
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+)
+
+// selectedUser returns the user currently selected in the user list.
+func (s *Session) selectedUser() (hotline.User, bool) {
+	i := s.userList.GetCurrentItem()
+	if i < 0 || i >= len(s.shownUsers) {
+		return hotline.User{}, false
+	}
+	return s.shownUsers[i], true
+}
+
+// showUserMenu shows the actions that can be taken on a user.
+func (s *Session) showUserMenu(u hotline.User) {
+	menu := tview.NewList().ShowSecondaryText(false)
+	menu.SetBorder(true).SetTitle(fmt.Sprintf("| %s |", tview.Escape(u.Name)))
+
+	closeMenu := func() {
+		s.Pages.RemovePage("userMenu")
+	}
+
+	if s.privileges.SendMessages {
+		menu.AddItem("Send Message", "", 'm', func() {
+			closeMenu()
+			s.showSendMessage(u)
+		})
+	}
+	if s.privileges.GetUserInfo {
+		menu.AddItem("Get Info", "", 'i', func() {
+			closeMenu()
+			s.getUserInfo(u)
+		})
+	}
+	if s.privileges.OpenChat {
+		menu.AddItem("Invite to Private Chat", "", 'c', func() {
+			closeMenu()
+			s.inviteToPrivateChat(u)
+		})
+	}
+	menu.AddItem("Ignore", "", 'x', func() {
+		closeMenu()
+		s.ignoreUser(u)
+	})
+	if s.privileges.DisconnectUsers {
+		menu.AddItem("Disconnect", "", 'd', func() {
+			closeMenu()
+			s.showDisconnectUser(u)
+		})
+	}
+	menu.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			closeMenu()
+			return nil
+		}
+		return event
+	})
+
+	s.Pages.AddPage("userMenu", centered(menu, 30, menu.GetItemCount()+2), true, true)
+}
+
+// showSendMessage shows a composer for sending a private message to the user.
+func (s *Session) showSendMessage(u hotline.User) {
+	msgInput := NewComposer()
+	msgInput.SetTextStyle(tcell.StyleDefault.Background(tcell.ColorDarkSlateGrey))
+	msgInput.SetBorder(true).SetTitle(fmt.Sprintf("| Message to %s |", tview.Escape(u.Name)))
+	msgInput.SetSubmitFunc(func(string) {
+		t := hotline.NewTransaction(hotline.TranSendInstantMsg, [2]byte{},
+			hotline.NewField(hotline.FieldUserID, u.ID[:]),
+			hotline.NewField(hotline.FieldOptions, []byte{0, 1}),
+			hotline.NewField(hotline.FieldData, s.encodeText(msgInput.HotlineText())),
+		)
+		if err := s.HLClient.Send(t); err != nil {
+			s.Logger.Error("Error sending private message", "err", err)
+		}
+		s.Pages.RemovePage("sendMessage")
+	})
+	msgInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.Pages.RemovePage("sendMessage")
+			return nil
+		}
+		return event
+	})
+
+	s.Pages.AddPage("sendMessage", centered(msgInput, 60, 10), true, true)
+}
+
+// ignoreUser adds an ignore rule for the user on the current server.
+func (s *Session) ignoreUser(u hotline.User) {
+	s.Pref.Ignore = append(s.Pref.Ignore, IgnoreRule{Name: u.Name, Server: s.serverAddr})
+	if err := s.savePrefs(); err != nil {
+		s.Logger.Error("Error saving ignore list", "err", err)
+	}
+	s.trackIgnoredUsers()
+}
+
+// HandleErrReply displays the error from a reply to a transaction that has no other reply content.
+func (s *Session) HandleErrReply(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if t.IsReply == 1 && t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+	}
+
+	return res, err
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"time"
+)
+
+// getUserInfo requests the client info text for the user.  On Mobius and most other servers this includes the user's
+// address, login, client version and file transfers in progress.
+func (s *Session) getUserInfo(u hotline.User) {
+	t := hotline.NewTransaction(hotline.TranGetClientInfoText, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, u.ID[:]),
+	)
+	s.userInfoRequests.add(t.ID, u)
+
+	if err := s.HLClient.Send(t); err != nil {
+		s.Logger.Error("Error requesting user info", "err", err)
+	}
+}
+
+// HandleGetClientInfoText displays the client info text returned by the server for a user.
+func (s *Session) HandleGetClientInfoText(ctx context.Context, c *hotline.Client, t *hotline.Transaction) (res []hotline.Transaction, err error) {
+	if u, ok := s.dashboardInfoRequests.take(t.ID); ok {
+		if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+			c.Logger.Debug("Error refreshing user info", "user", u.Name, "err", s.decodeText(t.GetField(hotline.FieldError).Data))
+			return res, err
+		}
+
+		// The dashboard belongs to the UI goroutine, which may have closed it since the request was sent.
+		info := parseClientInfo(crToLF(s.decodeText(t.GetField(hotline.FieldData).Data)))
+		s.App.QueueUpdateDraw(func() {
+			if s.dashboard != nil {
+				s.dashboard.info[u.ID] = info
+				s.renderDashboardTable()
+			}
+		})
+		return res, err
+	}
+
+	u, ok := s.userInfoRequests.take(t.ID)
+	if !ok {
+		return res, err
+	}
+
+	if t.ErrorCode != [4]byte{0, 0, 0, 0} {
+		s.showErrMsg(s.decodeText(t.GetField(hotline.FieldError).Data))
+		return res, err
+	}
+
+	name := s.decodeText(t.GetField(hotline.FieldUserName).Data)
+	info := crToLF(s.decodeText(t.GetField(hotline.FieldData).Data))
+
+	c.Logger.Info("Received user info", "server", s.serverAddr, "name", name, "info", info)
+
+	s.showUserInfo(u, name, info)
+
+	return res, err
+}
+
+// showUserInfo shows the info text for a user, replacing any info already shown.
+func (s *Session) showUserInfo(u hotline.User, name, info string) {
+	infoText := tview.NewTextView().
+		SetScrollable(true).
+		SetText(info)
+	infoText.SetBorder(true).SetTitle(fmt.Sprintf("| User Info: %s |", tview.Escape(name)))
+
+	footer := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(fmt.Sprintf("[yellow]r[-::]: Refresh   [yellow]c[-::]: Copy   [yellow]Esc[-::]: Close   [gray]Updated %s[-]", time.Now().Format("15:04:05")))
+
+	infoPage := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(infoText, 0, 1, true).
+		AddItem(footer, 1, 0, false)
+	infoPage.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			s.Pages.RemovePage("userInfo")
+			return nil
+		case event.Rune() == 'r':
+			s.getUserInfo(u)
+			return nil
+		case event.Rune() == 'c':
+			if err := copyToClipboard(info); err != nil {
+				s.Logger.Error("Error copying user info", "err", err)
+			}
+			return nil
+		}
+		return event
+	})
+
+	s.Pages.RemovePage("userInfo")
+	s.Pages.AddPage("userInfo", centered(infoPage, 70, 22), true, true)
+	s.App.Draw()
+}
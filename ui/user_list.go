@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// User list sort orders
+const (
+	userSortJoin  = "join"  // Order in which users joined the server
+	userSortName  = "name"  // Alphabetical by name
+	userSortAdmin = "admin" // Admins first
+	userSortAway  = "away"  // Away users last
+)
+
+var userSortOrders = []string{userSortJoin, userSortName, userSortAdmin, userSortAway}
+
+// defaultIconGlyph is shown for icon IDs without an entry in the glyph table.
+const defaultIconGlyph = "·"
+
+// defaultIconGlyphs maps some of the icon IDs in the classic Hotline client's icon set to a glyph for display in the
+// user list.  Entries can be added or overridden with ClientPrefs.IconGlyphs.
+var defaultIconGlyphs = map[int]string{
+	128:  "🙂",
+	129:  "😀",
+	130:  "😎",
+	131:  "😉",
+	132:  "😮",
+	133:  "😠",
+	134:  "😢",
+	135:  "😴",
+	136:  "🤓",
+	137:  "😈",
+	138:  "👽",
+	139:  "🤖",
+	140:  "💀",
+	141:  "🐱",
+	142:  "🐶",
+	143:  "🐻",
+	414:  "🌐",
+	2000: "🍎",
+}
+
+// iconGlyph returns the glyph for a user's icon ID.
+func (s *Session) iconGlyph(icon []byte) string {
+	if len(icon) != 2 {
+		return defaultIconGlyph
+	}
+	id := int(binary.BigEndian.Uint16(icon))
+
+	if glyph, ok := s.Pref.IconGlyphs[id]; ok {
+		return glyph
+	}
+	if glyph, ok := defaultIconGlyphs[id]; ok {
+		return glyph
+	}
+	return defaultIconGlyph
+}
+
+func userFlag(u hotline.User, flag int) bool {
+	if len(u.Flags) != 2 {
+		return false
+	}
+	return big.NewInt(int64(binary.BigEndian.Uint16(u.Flags))).Bit(flag) == 1
+}
+
+// sortUsers sorts users in place by the sort order.  Users that compare equal keep the order in which they joined.
+func sortUsers(users []hotline.User, order string) {
+	// compareBool orders users with the flag set before those without it.
+	compareBool := func(a, b bool) int {
+		switch {
+		case a == b:
+			return 0
+		case a:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	byName := func(a, b hotline.User) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}
+
+	switch order {
+	case userSortName:
+		slices.SortStableFunc(users, byName)
+	case userSortAdmin:
+		slices.SortStableFunc(users, func(a, b hotline.User) int {
+			return cmp.Or(compareBool(userFlag(a, hotline.UserFlagAdmin), userFlag(b, hotline.UserFlagAdmin)), byName(a, b))
+		})
+	case userSortAway:
+		slices.SortStableFunc(users, func(a, b hotline.User) int {
+			return cmp.Or(compareBool(!userFlag(a, hotline.UserFlagAway), !userFlag(b, hotline.UserFlagAway)), byName(a, b))
+		})
+	}
+}
+
+// cycleUserSort switches the user list to the next sort order.
+func (s *Session) cycleUserSort() {
+	i := slices.Index(userSortOrders, s.Pref.UserSort)
+	s.Pref.UserSort = userSortOrders[(i+1)%len(userSortOrders)]
+
+	if err := s.savePrefs(); err != nil {
+		s.Logger.Error("Error saving user list sort order", "err", err)
+	}
+
+	for _, session := range s.sessions {
+		session.renderUserList()
+	}
+}
+
+// newUserPane creates the user list and its filter input.
+func (s *Session) newUserPane() *tview.Flex {
+	s.userFilter = tview.NewInputField().
+		SetLabel("/").
+		SetFieldBackgroundColor(tcell.ColorDimGray).
+		SetPlaceholder("filter").
+		SetChangedFunc(func(string) {
+			s.renderUserList()
+		})
+	s.userFilter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			s.userFilter.SetText("")
+		}
+		s.App.SetFocus(s.userList)
+	})
+
+	s.userList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case '/':
+			s.App.SetFocus(s.userFilter)
+			return nil
+		case 's':
+			s.cycleUserSort()
+			return nil
+		}
+		return event
+	})
+
+	userPane := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(s.userFilter, 1, 0, false).
+		AddItem(s.userList, 0, 1, true)
+	userPane.SetBorder(true)
+
+	return userPane
+}
+
+func (s *Session) renderUserList() {
+	selected, hasSelection := s.selectedUser()
+
+	filter := strings.ToLower(s.userFilter.GetText())
+
+	s.shownUsers = s.shownUsers[:0]
+	for _, u := range s.UserList {
+		if filter == "" || strings.Contains(strings.ToLower(u.Name), filter) {
+			s.shownUsers = append(s.shownUsers, u)
+		}
+	}
+	sortUsers(s.shownUsers, s.Pref.UserSort)
+
+	sortOrder := cmp.Or(s.Pref.UserSort, userSortJoin)
+	s.userPane.SetTitle(fmt.Sprintf("Users (%d) · %s", len(s.UserList), sortOrder))
+
+	s.userList.Clear()
+	for i, u := range s.shownUsers {
+		// Away users are dimmed.
+		var attrs string
+		if userFlag(u, hotline.UserFlagAway) {
+			attrs = "d"
+		}
+
+		glyph := s.iconGlyph(u.Icon)
+		if userFlag(u, hotline.UserFlagAdmin) {
+			s.userList.AddItem(fmt.Sprintf("%s [red::b%s]%s[-:-:-]", glyph, attrs, tview.Escape(u.Name)), "", 0, nil)
+		} else {
+			s.userList.AddItem(fmt.Sprintf("%s [::%s]%s[-:-:-]", glyph, attrs, tview.Escape(u.Name)), "", 0, nil)
+		}
+
+		if hasSelection && u.ID == selected.ID {
+			s.userList.SetCurrentItem(i)
+		}
+	}
+}
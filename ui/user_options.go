@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/rivo/tview"
+)
+
+// refusePrivateMessages reports whether private messages should be refused on the current server.  A setting on the
+// server's bookmark overrides the global preference.
+func (s *Session) refusePrivateMessages() bool {
+	if s.bookmark != nil && s.bookmark.RefusePrivateMessages != nil {
+		return *s.bookmark.RefusePrivateMessages
+	}
+	return s.Pref.RefusePrivateMessages
+}
+
+// refusePrivateChat reports whether private chat invites should be refused on the current server.  A setting on the
+// server's bookmark overrides the global preference.
+func (s *Session) refusePrivateChat() bool {
+	if s.bookmark != nil && s.bookmark.RefusePrivateChat != nil {
+		return *s.bookmark.RefusePrivateChat
+	}
+	return s.Pref.RefusePrivateChat
+}
+
+// userOptions returns the options bitmap sent to the server in TranAgreed and TranSetClientUserInfo.
+func (s *Session) userOptions() hotline.UserFlags {
+	// Options use the same two byte bitmap layout as user flags.
+	var options hotline.UserFlags
+	if s.refusePrivateMessages() {
+		options.Set(hotline.UserOptRefusePM, 1)
+	}
+	if s.refusePrivateChat() {
+		options.Set(hotline.UserOptRefuseChat, 1)
+	}
+	if s.away && s.Pref.AutoResponse != "" {
+		options.Set(hotline.UserOptAutoResponse, 1)
+	}
+
+	return options
+}
+
+// sendUserInfo sends our name, icon, away status and options to the server.
+//
+// Not all servers honor the away flag sent by clients; some instead mark users away after their own idle timeout.
+func (s *Session) sendUserInfo() error {
+	var flags hotline.UserFlags
+	if s.away {
+		flags.Set(hotline.UserFlagAway, 1)
+	}
+
+	options := s.userOptions()
+
+	fields := []hotline.Field{
+		hotline.NewField(hotline.FieldUserName, s.encodeText(s.username())),
+		hotline.NewField(hotline.FieldUserIconID, s.iconBytes()),
+		hotline.NewField(hotline.FieldUserFlags, flags[:]),
+		hotline.NewField(hotline.FieldOptions, options[:]),
+	}
+	if options.IsSet(hotline.UserOptAutoResponse) {
+		fields = append(fields, hotline.NewField(hotline.FieldAutomaticResponse, s.encodeText(s.Pref.AutoResponse)))
+	}
+
+	return s.HLClient.Send(hotline.NewTransaction(hotline.TranSetClientUserInfo, [2]byte{}, fields...))
+}
+
+// renderServerOptions renders a form for changing the private message and chat options for the current server.  The
+// options are saved to the server's bookmark, or to the global preferences if the server isn't bookmarked.
+func (s *Session) renderServerOptions() *tview.Flex {
+	title := "| Options |"
+	if s.bookmark != nil {
+		title = fmt.Sprintf("| Options for %s |", tview.Escape(s.bookmark.Name))
+	}
+
+	optionsForm := tview.NewForm()
+	optionsForm.
+		AddCheckbox("Refuse private messages", s.refusePrivateMessages(), nil).
+		AddCheckbox("Refuse private chat", s.refusePrivateChat(), nil)
+	if s.bookmark != nil {
+		optionsForm.
+			AddCheckbox("Show user notices in chat", !s.bookmark.HideChatNotices, nil).
+			AddCheckbox("Reconnect automatically", s.bookmark.AutoReconnect, nil)
+	}
+	optionsForm.
+		AddButton("Save", func() {
+			refusePM := optionsForm.GetFormItem(0).(*tview.Checkbox).IsChecked()
+			refuseChat := optionsForm.GetFormItem(1).(*tview.Checkbox).IsChecked()
+
+			if s.bookmark != nil {
+				s.bookmark.RefusePrivateMessages = &refusePM
+				s.bookmark.RefusePrivateChat = &refuseChat
+				s.bookmark.HideChatNotices = !optionsForm.GetFormItem(2).(*tview.Checkbox).IsChecked()
+				s.bookmark.AutoReconnect = optionsForm.GetFormItem(3).(*tview.Checkbox).IsChecked()
+			} else {
+				s.Pref.RefusePrivateMessages = refusePM
+				s.Pref.RefusePrivateChat = refuseChat
+			}
+
+			if err := s.savePrefs(); err != nil {
+				s.Logger.Error("Error saving options", "err", err)
+			}
+			if err := s.sendUserInfo(); err != nil {
+				s.Logger.Error("Error sending options", "err", err)
+			}
+
+			s.Pages.RemovePage("serverOptions")
+		}).
+		AddButton("My Privileges", func() {
+			s.Pages.RemovePage("serverOptions")
+			s.Pages.AddPage("privileges", s.renderPrivileges(), true, true)
+		})
+	optionsForm.SetBorder(true).SetTitle(title)
+	optionsForm.SetCancelFunc(func() {
+		s.Pages.RemovePage("serverOptions")
+	})
+	optionsForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			s.Pages.RemovePage("serverOptions")
+			return nil
+		}
+		return event
+	})
+
+	return centered(optionsForm, 45, 13)
+}
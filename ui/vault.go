@@ -0,0 +1,398 @@
+package ui
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/rivo/tview"
+	"golang.org/x/crypto/argon2"
+	"gopkg.in/yaml.v3"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Argon2id parameters for deriving the vault key from the passphrase, as recommended by RFC 9106 for memory
+// constrained environments.
+const (
+	vaultKDF        = "argon2id"
+	vaultKDFTime    = 3
+	vaultKDFMemory  = 64 * 1024 // KiB
+	vaultKDFThreads = 4
+	vaultKeyLen     = 32 // AES-256
+	vaultSaltLen    = 16
+)
+
+// Limits on the KDF parameters read from a vault file, so that a corrupt or tampered file can't make opening it take
+// gigabytes of memory or minutes of CPU.  They're well above the parameters new vaults are created with.
+const (
+	vaultMaxTime    = 16
+	vaultMaxMemory  = 1024 * 1024 // KiB
+	vaultMaxThreads = 16
+)
+
+// minPassphraseLen is the shortest passphrase accepted for a new vault.
+const minPassphraseLen = 8
+
+var (
+	errWrongPassphrase = errors.New("wrong passphrase")
+	errVaultLocked     = errors.New("password vault is locked")
+)
+
+// vaultFile is the on-disk format of the credential vault.  The entries are encrypted with AES-256-GCM using a key
+// derived from the passphrase, with the KDF parameters stored alongside so they can be raised later.
+type vaultFile struct {
+	Version    int    `yaml:"Version"`
+	KDF        string `yaml:"KDF"`
+	Time       uint32 `yaml:"Time"`
+	Memory     uint32 `yaml:"Memory"`
+	Threads    uint8  `yaml:"Threads"`
+	Salt       []byte `yaml:"Salt"`
+	Nonce      []byte `yaml:"Nonce"`
+	Ciphertext []byte `yaml:"Ciphertext"`
+}
+
+// vault is an unlocked credential vault, holding bookmark passwords keyed by entry ID.
+type vault struct {
+	path    string
+	file    vaultFile
+	key     []byte
+	entries map[string]string
+}
+
+// vaultPath returns the path of the credential vault, which is kept next to the config file.
+func vaultPath(cfgPath string) string {
+	base := strings.TrimSuffix(filepath.Base(cfgPath), filepath.Ext(cfgPath))
+	return filepath.Join(filepath.Dir(cfgPath), base+"-vault.yaml")
+}
+
+// newVault creates an empty vault locked with the passphrase.  It isn't written until saved.
+func newVault(path, passphrase string) (*vault, error) {
+	salt := make([]byte, vaultSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	v := &vault{
+		path: path,
+		file: vaultFile{
+			Version: 1,
+			KDF:     vaultKDF,
+			Time:    vaultKDFTime,
+			Memory:  vaultKDFMemory,
+			Threads: vaultKDFThreads,
+			Salt:    salt,
+		},
+		entries: make(map[string]string),
+	}
+	v.key = v.file.deriveKey(passphrase)
+
+	return v, nil
+}
+
+// openVault reads and decrypts the vault.
+func openVault(path, passphrase string) (*vault, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vault{path: path}
+	if err := yaml.Unmarshal(data, &v.file); err != nil {
+		return nil, fmt.Errorf("parse vault: %w", err)
+	}
+	if v.file.Version != 1 || v.file.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported vault version %d (%s)", v.file.Version, v.file.KDF)
+	}
+	if err := v.file.checkKDF(); err != nil {
+		return nil, err
+	}
+	v.key = v.file.deriveKey(passphrase)
+
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, v.file.Nonce, v.file.Ciphertext, nil)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+
+	if err := yaml.Unmarshal(plaintext, &v.entries); err != nil {
+		return nil, fmt.Errorf("parse vault entries: %w", err)
+	}
+	if v.entries == nil {
+		v.entries = make(map[string]string)
+	}
+
+	return v, nil
+}
+
+// checkKDF returns an error if the KDF parameters are out of bounds.
+func (f *vaultFile) checkKDF() error {
+	switch {
+	case f.Time < 1 || f.Time > vaultMaxTime:
+		return fmt.Errorf("invalid vault KDF time %d", f.Time)
+	case f.Threads < 1 || f.Threads > vaultMaxThreads:
+		return fmt.Errorf("invalid vault KDF threads %d", f.Threads)
+	case f.Memory < 8*uint32(f.Threads) || f.Memory > vaultMaxMemory:
+		return fmt.Errorf("invalid vault KDF memory %d KiB", f.Memory)
+	case len(f.Salt) < vaultSaltLen:
+		return errors.New("vault salt is too short")
+	}
+	return nil
+}
+
+func (f *vaultFile) deriveKey(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), f.Salt, f.Time, f.Memory, f.Threads, vaultKeyLen)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// save encrypts the entries with a fresh nonce and writes the vault.
+func (v *vault) save() error {
+	plaintext, err := yaml.Marshal(v.entries)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	v.file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(v.file.Nonce); err != nil {
+		return err
+	}
+	v.file.Ciphertext = gcm.Seal(nil, v.file.Nonce, plaintext, nil)
+
+	out, err := yaml.Marshal(&v.file)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(v.path, out, 0600)
+}
+
+// newVaultRef returns a random ID for a vault entry.
+func newVaultRef() string {
+	ref := make([]byte, 8)
+	_, _ = rand.Read(ref)
+	return hex.EncodeToString(ref)
+}
+
+// vaultExists reports whether the vault file has been created.
+func (mhc *Client) vaultExists() bool {
+	_, err := os.Stat(vaultPath(mhc.CfgPath))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// openOrCreateVault unlocks the vault with the passphrase, creating the vault if there is none yet.
+func (mhc *Client) openOrCreateVault(passphrase string) error {
+	path := vaultPath(mhc.CfgPath)
+
+	if mhc.vaultExists() {
+		v, err := openVault(path, passphrase)
+		if err != nil {
+			return err
+		}
+		mhc.vault = v
+		return nil
+	}
+
+	if len(passphrase) < minPassphraseLen {
+		return fmt.Errorf("use at least %d characters", minPassphraseLen)
+	}
+	v, err := newVault(path, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := v.save(); err != nil {
+		return err
+	}
+	mhc.vault = v
+	return nil
+}
+
+// unlockVault calls then once the vault is unlocked, asking for the passphrase if it's locked, or for a new passphrase
+// if there is no vault yet.  cancelled, if not nil, is called instead if the user cancels.
+func (mhc *Client) unlockVault(then, cancelled func()) {
+	if mhc.vault != nil {
+		then()
+		return
+	}
+
+	create := !mhc.vaultExists()
+
+	form := tview.NewForm()
+	form.AddPasswordField("Passphrase", "", 0, '*', nil)
+	if create {
+		form.AddPasswordField("Confirm", "", 0, '*', nil)
+	}
+
+	fail := func(msg string) {
+		form.SetTitle(fmt.Sprintf("| %s |", msg))
+		form.SetFocus(0)
+	}
+
+	form.
+		AddButton("Unlock", func() {
+			passphrase := form.GetFormItem(0).(*tview.InputField).GetText()
+			if create && passphrase != form.GetFormItem(1).(*tview.InputField).GetText() {
+				fail("Passphrases don't match")
+				return
+			}
+
+			err := mhc.openOrCreateVault(passphrase)
+			if errors.Is(err, errWrongPassphrase) {
+				fail("Wrong passphrase")
+				return
+			}
+			if err != nil {
+				mhc.Logger.Error("Error opening vault", "err", err)
+				fail(err.Error())
+				return
+			}
+
+			mhc.Pages.RemovePage("unlockVault")
+			then()
+		}).
+		AddButton("Cancel", func() {
+			mhc.Pages.RemovePage("unlockVault")
+			if cancelled != nil {
+				cancelled()
+			}
+		})
+	form.SetCancelFunc(func() {
+		mhc.Pages.RemovePage("unlockVault")
+		if cancelled != nil {
+			cancelled()
+		}
+	})
+
+	title := "| Unlock Password Vault |"
+	height := 7
+	if create {
+		title = "| Create Password Vault |"
+		height = 9
+	}
+	form.SetBorder(true).SetTitle(title)
+
+	mhc.Pages.AddPage("unlockVault", centered(form, 50, height), true, true)
+}
+
+// bookmarkPassword calls then with the bookmark's password, unlocking the vault first if the password is in it.
+// cancelled, if not nil, is called instead if the user doesn't unlock the vault.
+func (mhc *Client) bookmarkPassword(b Bookmark, then func(password string), cancelled func()) {
+	if b.PasswordRef == "" {
+		then(b.Password)
+		return
+	}
+
+	mhc.unlockVault(func() {
+		then(mhc.vault.entries[b.PasswordRef])
+	}, cancelled)
+}
+
+// setBookmarkPassword stores the password of the bookmark in the vault, or removes it if the password is empty.  The
+// vault must be unlocked to store a password; removing one while it's locked leaves the entry in the vault, where it's
+// harmless, just unreachable.  The caller saves the bookmark.
+func (mhc *Client) setBookmarkPassword(b *Bookmark, password string) error {
+	if password != "" && mhc.vault == nil {
+		return errVaultLocked
+	}
+	b.Password = ""
+
+	if password == "" {
+		ref := b.PasswordRef
+		b.PasswordRef = ""
+		if ref == "" || mhc.vault == nil {
+			return nil
+		}
+		delete(mhc.vault.entries, ref)
+	} else {
+		if b.PasswordRef == "" {
+			b.PasswordRef = newVaultRef()
+		}
+		mhc.vault.entries[b.PasswordRef] = password
+	}
+
+	return mhc.vault.save()
+}
+
+// plaintextPasswords returns the number of bookmarks that have a password outside the vault.
+func (cp *ClientPrefs) plaintextPasswords() int {
+	var n int
+	for _, b := range cp.Bookmarks {
+		if b.Password != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// migratePasswords moves plaintext bookmark passwords into the vault, which must be unlocked.  If the vault can't be
+// saved, the bookmarks and vault are left as they were, with the passwords still in the config.
+func (mhc *Client) migratePasswords() error {
+	bookmarks := slices.Clone(mhc.Pref.Bookmarks)
+	entries := maps.Clone(mhc.vault.entries)
+
+	for i := range mhc.Pref.Bookmarks {
+		b := &mhc.Pref.Bookmarks[i]
+		if b.Password == "" {
+			continue
+		}
+
+		if b.PasswordRef == "" {
+			b.PasswordRef = newVaultRef()
+		}
+		mhc.vault.entries[b.PasswordRef] = b.Password
+		b.Password = ""
+	}
+
+	// Write the vault before the config, so the passwords are never only in memory.
+	if err := mhc.vault.save(); err != nil {
+		mhc.Pref.Bookmarks = bookmarks
+		mhc.vault.entries = entries
+		return fmt.Errorf("save vault: %w", err)
+	}
+	mhc.bookmarksChanged()
+
+	return nil
+}
+
+// offerPasswordMigration offers to move plaintext bookmark passwords into the vault.
+func (mhc *Client) offerPasswordMigration() {
+	n := mhc.Pref.plaintextPasswords()
+	if n == 0 {
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("%d bookmarks store their passwords in plain text in the config file.\n\nMove them into an encrypted password vault?", n)).
+		AddButtons([]string{"Not Now", "Encrypt"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			mhc.Pages.RemovePage("migratePasswords")
+			if buttonIndex == 1 {
+				mhc.unlockVault(func() {
+					if err := mhc.migratePasswords(); err != nil {
+						mhc.Logger.Error("Error moving passwords into the vault", "err", err)
+						mhc.addErrMsg(fmt.Sprintf("The passwords were left in the config file.\n\n%s", err))
+					}
+				}, nil)
+			}
+		})
+	modal.Box.SetTitle("Plaintext Passwords")
+
+	mhc.Pages.AddPage("migratePasswords", modal, false, true)
+}
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client-vault.yaml")
+
+	v, err := newVault(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.entries["a1"] = "hunter2"
+	v.entries["b2"] = "pässwörd"
+	if err := v.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pw := range v.entries {
+		if bytes.Contains(data, []byte(pw)) {
+			t.Errorf("vault file contains password %q in plain text", pw)
+		}
+	}
+
+	opened, err := openVault(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened.entries) != len(v.entries) {
+		t.Fatalf("opened %d entries, want %d", len(opened.entries), len(v.entries))
+	}
+	for ref, pw := range v.entries {
+		if opened.entries[ref] != pw {
+			t.Errorf("entry %s = %q, want %q", ref, opened.entries[ref], pw)
+		}
+	}
+}
+
+func TestOpenVaultWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client-vault.yaml")
+
+	v, err := newVault(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openVault(path, "battery staple"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("openVault with wrong passphrase: err = %v, want %v", err, errWrongPassphrase)
+	}
+}
+
+func TestOpenVaultKDFBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *vaultFile)
+	}{
+		{name: "time", modify: func(f *vaultFile) { f.Time = vaultMaxTime + 1 }},
+		{name: "memory", modify: func(f *vaultFile) { f.Memory = vaultMaxMemory + 1 }},
+		{name: "threads", modify: func(f *vaultFile) { f.Threads = 0 }},
+		{name: "salt", modify: func(f *vaultFile) { f.Salt = f.Salt[:4] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "client-vault.yaml")
+
+			v, err := newVault(path, "correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := v.save(); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.modify(&v.file)
+			out, err := yaml.Marshal(&v.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, out, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := openVault(path, "correct horse"); err == nil {
+				t.Error("openVault accepted out of bounds KDF parameters")
+			}
+		})
+	}
+}
+
+func TestSetBookmarkPasswordLocked(t *testing.T) {
+	mhc := &Client{}
+	b := Bookmark{Name: "test", Password: "plain"}
+
+	if err := mhc.setBookmarkPassword(&b, "hunter2"); !errors.Is(err, errVaultLocked) {
+		t.Fatalf("setBookmarkPassword with locked vault: err = %v, want %v", err, errVaultLocked)
+	}
+	if b.Password != "plain" || b.PasswordRef != "" {
+		t.Errorf("setBookmarkPassword with locked vault changed the bookmark: %+v", b)
+	}
+}
+
+func TestMigratePasswordsSaveError(t *testing.T) {
+	// The vault can't be written to a directory that doesn't exist.
+	v, err := newVault(filepath.Join(t.TempDir(), "missing", "client-vault.yaml"), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mhc := &Client{
+		Pref:  &ClientPrefs{Bookmarks: []Bookmark{{Name: "test", Addr: "hl.example.com", Password: "hunter2"}}},
+		vault: v,
+	}
+
+	if err := mhc.migratePasswords(); err == nil {
+		t.Fatal("migratePasswords succeeded without saving the vault")
+	}
+	if b := mhc.Pref.Bookmarks[0]; b.Password != "hunter2" || b.PasswordRef != "" {
+		t.Errorf("migratePasswords changed the bookmark after failing: %+v", b)
+	}
+	if len(v.entries) != 0 {
+		t.Errorf("migratePasswords left vault entries after failing: %v", v.entries)
+	}
+}